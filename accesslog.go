@@ -0,0 +1,18 @@
+package traceparent
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// AccessLog returns the middleware configured by opts, see [New], that
+// in addition logs a line with the method, path, status, body bytes and
+// duration of each request to logger once next returns. It is logged
+// with the request context, so the extractor adds the trace
+// attributes. Requests skipped by [WithSkip] are not logged.
+func AccessLog(next http.Handler, logger *slog.Logger, opts ...Option) http.Handler {
+	cfg := newConfig(opts)
+	cfg.responseLogger = logger
+	cfg.accessLog = true
+	return newHandler(next, cfg)
+}
@@ -0,0 +1,46 @@
+package traceparent_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	traceparent "github.com/jum/slog-traceparent"
+)
+
+func TestAccessLogBehindWrap(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(&traceparent.Handler{Next: slog.NewJSONHandler(&buf, nil)})
+	mux := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("short"))
+	})
+	handler := traceparent.Wrap(traceparent.AccessLog(mux, logger))
+	r := httptest.NewRequest(http.MethodPost, "/brew", nil)
+	r.Header.Set("traceparent", validHeader)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("access log %q: %v", buf.String(), err)
+	}
+	want := map[string]any{
+		"msg":     "request",
+		"method":  http.MethodPost,
+		"path":    "/brew",
+		"status":  float64(http.StatusTeapot),
+		"bytes":   float64(len("short")),
+		"traceID": traceID,
+	}
+	for key, value := range want {
+		if line[key] != value {
+			t.Errorf("%s = %v, want %v", key, line[key], value)
+		}
+	}
+	if _, ok := line["duration"]; !ok {
+		t.Error("duration missing")
+	}
+}
@@ -0,0 +1,48 @@
+// Package amqpheaders propagates the [traceparent.Trace] over the
+// headers of AMQP messages, like those of RabbitMQ deliveries.
+//
+// Table has the same underlying type as amqp.Table of
+// github.com/rabbitmq/amqp091-go, so the headers of a delivery or
+// publishing convert directly without this module depending on an AMQP
+// client:
+//
+//	trace, ok := amqpheaders.Extract(amqpheaders.Table(delivery.Headers))
+package amqpheaders
+
+import (
+	traceparent "github.com/jum/slog-traceparent"
+)
+
+// Table adapts an AMQP header table to the [traceparent.Carrier]
+// interface.
+type Table map[string]any
+
+// Get implements [traceparent.Carrier]. Values of type string and
+// []byte are returned as string, any other type as an empty string.
+func (t Table) Get(key string) string {
+	switch v := t[key].(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return ""
+}
+
+// Set implements [traceparent.Carrier].
+func (t Table) Set(key, value string) {
+	t[key] = value
+}
+
+// Extract parses the traceparent and tracestate headers of a received
+// message into a [traceparent.Trace] and reports whether a valid trace
+// was found.
+func Extract(headers Table) (traceparent.Trace, bool) {
+	return traceparent.Extract(headers)
+}
+
+// Inject adds the traceparent and tracestate headers for trace to the
+// headers of a message to be published, which must not be nil.
+func Inject(trace traceparent.Trace, headers Table) {
+	traceparent.Inject(trace, headers)
+}
@@ -0,0 +1,54 @@
+package amqpheaders_test
+
+import (
+	"testing"
+
+	traceparent "github.com/jum/slog-traceparent"
+	"github.com/jum/slog-traceparent/amqpheaders"
+)
+
+const header = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers amqpheaders.Table
+		ok      bool
+	}{
+		{"string", amqpheaders.Table{"traceparent": header, "tracestate": "vendor=value"}, true},
+		{"bytes", amqpheaders.Table{"traceparent": []byte(header), "tracestate": []byte("vendor=value")}, true},
+		{"missing", amqpheaders.Table{"other": header}, false},
+		{"nil table", nil, false},
+		{"other type", amqpheaders.Table{"traceparent": 42}, false},
+		{"malformed", amqpheaders.Table{"traceparent": "00-xyz-1-01"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trace, ok := amqpheaders.Extract(tt.headers)
+			if ok != tt.ok {
+				t.Fatalf("Extract = %v, %v, want %v", trace, ok, tt.ok)
+			}
+			if ok && (trace.Header() != header || trace.State != "vendor=value") {
+				t.Errorf("trace %v state %q", trace, trace.State)
+			}
+		})
+	}
+}
+
+func TestInject(t *testing.T) {
+	trace, err := traceparent.ParseTraceparent(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers := amqpheaders.Table{}
+	amqpheaders.Inject(trace, headers)
+	if headers["traceparent"] != header {
+		t.Errorf("traceparent header %v", headers["traceparent"])
+	}
+	if _, ok := headers["tracestate"]; ok {
+		t.Error("empty tracestate set")
+	}
+	if got, ok := amqpheaders.Extract(headers); !ok || !got.Equal(trace) {
+		t.Errorf("round trip got %v", got)
+	}
+}
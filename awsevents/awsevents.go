@@ -0,0 +1,87 @@
+// Package awsevents propagates the [traceparent.Trace] over the message
+// attributes of Amazon SQS and SNS messages.
+//
+// To keep the AWS SDK dependency out of this module, the attribute
+// types mirror the field layout of the types in
+// github.com/aws/aws-lambda-go/events and values of those can be
+// converted directly:
+//
+//	attrs := make(awsevents.MessageAttributes, len(msg.MessageAttributes))
+//	for key, attr := range msg.MessageAttributes {
+//		attrs[key] = awsevents.MessageAttribute(attr)
+//	}
+//	trace, ok := awsevents.Extract(attrs)
+package awsevents
+
+import (
+	traceparent "github.com/jum/slog-traceparent"
+)
+
+// MessageAttribute is a message attribute of an SQS message, laid out
+// like events.SQSMessageAttribute.
+type MessageAttribute struct {
+	StringValue      *string  `json:"stringValue,omitempty"`
+	BinaryValue      []byte   `json:"binaryValue,omitempty"`
+	StringListValues []string `json:"stringListValues"`
+	BinaryListValues [][]byte `json:"binaryListValues"`
+	DataType         string   `json:"dataType"`
+}
+
+// MessageAttributes adapts the attributes of an SQS message to the
+// [traceparent.Carrier] interface. Only attributes of the String data
+// type are read.
+type MessageAttributes map[string]MessageAttribute
+
+// Get implements [traceparent.Carrier].
+func (attrs MessageAttributes) Get(key string) string {
+	attr, ok := attrs[key]
+	if !ok || attr.DataType != "String" || attr.StringValue == nil {
+		return ""
+	}
+	return *attr.StringValue
+}
+
+// Set implements [traceparent.Carrier].
+func (attrs MessageAttributes) Set(key, value string) {
+	attrs[key] = MessageAttribute{StringValue: &value, DataType: "String"}
+}
+
+// SNSMessageAttribute is a message attribute of an SNS notification,
+// laid out like events.SNSMessageAttribute.
+type SNSMessageAttribute struct {
+	Type  string `json:"Type"`
+	Value string `json:"Value"`
+}
+
+// SNSMessageAttributes adapts the attributes of an SNS notification to
+// the [traceparent.Carrier] interface. Only attributes of the String
+// type are read.
+type SNSMessageAttributes map[string]SNSMessageAttribute
+
+// Get implements [traceparent.Carrier].
+func (attrs SNSMessageAttributes) Get(key string) string {
+	attr, ok := attrs[key]
+	if !ok || attr.Type != "String" {
+		return ""
+	}
+	return attr.Value
+}
+
+// Set implements [traceparent.Carrier].
+func (attrs SNSMessageAttributes) Set(key, value string) {
+	attrs[key] = SNSMessageAttribute{Type: "String", Value: value}
+}
+
+// Extract parses the traceparent and tracestate attributes of a
+// received message into a [traceparent.Trace] and reports whether a
+// valid trace was found. Malformed values are treated as absent.
+func Extract(attrs traceparent.Carrier) (traceparent.Trace, bool) {
+	return traceparent.Extract(attrs)
+}
+
+// Inject adds the traceparent and tracestate attributes for trace to
+// the attributes of a message to be published. Note that SQS and SNS
+// limit a message to ten attributes.
+func Inject(trace traceparent.Trace, attrs traceparent.Carrier) {
+	traceparent.Inject(trace, attrs)
+}
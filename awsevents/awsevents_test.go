@@ -0,0 +1,106 @@
+package awsevents_test
+
+import (
+	"testing"
+
+	traceparent "github.com/jum/slog-traceparent"
+	"github.com/jum/slog-traceparent/awsevents"
+)
+
+const header = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func str(s string) *string {
+	return &s
+}
+
+func TestSQS(t *testing.T) {
+	tests := []struct {
+		name  string
+		attrs awsevents.MessageAttributes
+		ok    bool
+	}{
+		{"present", awsevents.MessageAttributes{
+			"traceparent": {StringValue: str(header), DataType: "String"},
+			"tracestate":  {StringValue: str("vendor=value"), DataType: "String"},
+		}, true},
+		{"absent", awsevents.MessageAttributes{
+			"other": {StringValue: str("value"), DataType: "String"},
+		}, false},
+		{"malformed", awsevents.MessageAttributes{
+			"traceparent": {StringValue: str("00-xyz-1-01"), DataType: "String"},
+		}, false},
+		{"binary", awsevents.MessageAttributes{
+			"traceparent": {BinaryValue: []byte(header), DataType: "Binary"},
+		}, false},
+		{"nil string", awsevents.MessageAttributes{
+			"traceparent": {DataType: "String"},
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trace, ok := awsevents.Extract(tt.attrs)
+			if ok != tt.ok {
+				t.Fatalf("Extract = %v, %v, want %v", trace, ok, tt.ok)
+			}
+			if ok && (trace.Header() != header || trace.State != "vendor=value") {
+				t.Errorf("trace %v state %q", trace, trace.State)
+			}
+		})
+	}
+}
+
+func TestSNS(t *testing.T) {
+	tests := []struct {
+		name  string
+		attrs awsevents.SNSMessageAttributes
+		ok    bool
+	}{
+		{"present", awsevents.SNSMessageAttributes{
+			"traceparent": {Type: "String", Value: header},
+			"tracestate":  {Type: "String", Value: "vendor=value"},
+		}, true},
+		{"absent", awsevents.SNSMessageAttributes{
+			"other": {Type: "String", Value: "value"},
+		}, false},
+		{"malformed", awsevents.SNSMessageAttributes{
+			"traceparent": {Type: "String", Value: "00-xyz-1-01"},
+		}, false},
+		{"binary", awsevents.SNSMessageAttributes{
+			"traceparent": {Type: "Binary", Value: header},
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trace, ok := awsevents.Extract(tt.attrs)
+			if ok != tt.ok {
+				t.Fatalf("Extract = %v, %v, want %v", trace, ok, tt.ok)
+			}
+			if ok && (trace.Header() != header || trace.State != "vendor=value") {
+				t.Errorf("trace %v state %q", trace, trace.State)
+			}
+		})
+	}
+}
+
+func TestInject(t *testing.T) {
+	trace, err := traceparent.ParseTraceparent(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trace = trace.WithState("vendor=value")
+	sqs := awsevents.MessageAttributes{}
+	awsevents.Inject(trace, sqs)
+	if attr := sqs["traceparent"]; attr.DataType != "String" || *attr.StringValue != header {
+		t.Errorf("SQS traceparent attribute %+v", attr)
+	}
+	sns := awsevents.SNSMessageAttributes{}
+	awsevents.Inject(trace, sns)
+	if attr := sns["tracestate"]; attr.Type != "String" || attr.Value != "vendor=value" {
+		t.Errorf("SNS tracestate attribute %+v", attr)
+	}
+	for _, carrier := range []traceparent.Carrier{sqs, sns} {
+		if got, ok := awsevents.Extract(carrier); !ok || !got.Equal(trace) || got.State != trace.State {
+			t.Errorf("round trip got %v", got)
+		}
+	}
+}
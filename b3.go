@@ -0,0 +1,58 @@
+package traceparent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseB3 parses the value of a Zipkin B3 single header of the form
+// traceid-spanid[-sampled[-parentspanid]] into a [Trace]. 64 bit
+// trace ids are left padded with zeros to the 128 bit W3C form.
+func ParseB3(header string) (Trace, error) {
+	b3 := strings.Split(header, "-")
+	if len(b3) < 2 || len(b3) > 4 {
+		return Trace{}, fmt.Errorf("%w: %d b3 fields", ErrMalformed, len(b3))
+	}
+	sampled := ""
+	if len(b3) > 2 {
+		sampled = b3[2]
+	}
+	return newB3Trace(b3[0], b3[1], sampled)
+}
+
+// ExtractB3 parses the B3 values of carrier, either the single b3
+// header or the multi header X-B3-TraceId, X-B3-SpanId and X-B3-Sampled
+// form, into a [Trace].
+func ExtractB3(carrier Carrier) (Trace, error) {
+	if header := carrier.Get("b3"); header != "" {
+		return ParseB3(header)
+	}
+	sampled := carrier.Get("X-B3-Sampled")
+	if carrier.Get("X-B3-Flags") == "1" {
+		sampled = "d"
+	}
+	return newB3Trace(carrier.Get("X-B3-TraceId"), carrier.Get("X-B3-SpanId"), sampled)
+}
+
+func newB3Trace(id, spanID, sampled string) (Trace, error) {
+	if len(id) == 16 {
+		id = strings.Repeat("0", 16) + id
+	}
+	traceID, err := ParseTraceID(id)
+	if err != nil {
+		return Trace{}, err
+	}
+	trace := Trace{ID: traceID}
+	if trace.SpanID, err = ParseSpanID(spanID); err != nil {
+		return Trace{}, err
+	}
+	switch sampled {
+	case "1", "d", "true":
+		trace.Flags = FlagSampled
+		trace.Sampled = true
+	case "", "0", "false":
+	default:
+		return Trace{}, fmt.Errorf("%w: b3 sampling state %q", ErrInvalidFlags, sampled)
+	}
+	return trace, nil
+}
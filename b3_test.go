@@ -0,0 +1,64 @@
+package traceparent_test
+
+import (
+	"net/http"
+	"testing"
+
+	traceparent "github.com/jum/slog-traceparent"
+)
+
+func TestB3Fallback(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  http.Header
+		id      string
+		sampled bool
+	}{
+		{"single", http.Header{"b3": {traceID + "-" + spanID + "-1"}}, traceID, true},
+		{"single 64 bit", http.Header{"b3": {"a3ce929d0e0e4736-" + spanID + "-0"}}, "0000000000000000a3ce929d0e0e4736", false},
+		{"single with parent", http.Header{"b3": {traceID + "-" + spanID + "-d-b7ad6b7169203331"}}, traceID, true},
+		{"multi", http.Header{
+			"X-B3-TraceId": {traceID},
+			"X-B3-SpanId":  {spanID},
+			"X-B3-Sampled": {"1"},
+		}, traceID, true},
+		{"multi 64 bit", http.Header{
+			"X-B3-TraceId": {"a3ce929d0e0e4736"},
+			"X-B3-SpanId":  {spanID},
+		}, "0000000000000000a3ce929d0e0e4736", false},
+		{"traceparent first", http.Header{
+			"traceparent": {"00-0af7651916cd43dd8448eb211c80319c-" + spanID + "-01"},
+			"b3":          {traceID + "-" + spanID + "-0"},
+		}, "0af7651916cd43dd8448eb211c80319c", true},
+		{"invalid traceparent", http.Header{
+			"traceparent": {"00-xyz"},
+			"b3":          {traceID + "-" + spanID + "-1"},
+		}, traceID, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got, _ := serve(t, []traceparent.Option{traceparent.WithB3Fallback()}, tt.header)
+			if got.ID.String() != tt.id || got.SpanID.String() != spanID || got.Sampled != tt.sampled {
+				t.Errorf("trace %v, want %s-%s sampled %v", got, tt.id, spanID, tt.sampled)
+			}
+		})
+	}
+	if _, got, _ := serve(t, nil, http.Header{"b3": {traceID + "-" + spanID + "-1"}}); got.Valid() {
+		t.Errorf("b3 parsed without the option: %v", got)
+	}
+}
+
+func TestParseB3Invalid(t *testing.T) {
+	for _, header := range []string{
+		"",
+		traceID,
+		traceID + "-" + spanID + "-x",
+		"a3ce929d0e0e47-" + spanID,
+		traceID + "-" + spanID + "-1-" + spanID + "-extra",
+		"00000000000000000000000000000000-" + spanID,
+	} {
+		if trace, err := traceparent.ParseB3(header); err == nil {
+			t.Errorf("ParseB3(%q) = %v, want an error", header, trace)
+		}
+	}
+}
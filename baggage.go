@@ -0,0 +1,86 @@
+package traceparent
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxBaggageLen is the maximum length of a baggage header defined by
+// the W3C baggage specification, longer headers are ignored.
+const maxBaggageLen = 8192
+
+// ParseBaggage parses the value of a W3C baggage header into a map of
+// its comma-separated key=value list members. Values are percent
+// decoded and member properties are discarded. Malformed list members
+// are skipped.
+func ParseBaggage(header string) map[string]string {
+	if header == "" || len(header) > maxBaggageLen {
+		return nil
+	}
+	baggage := make(map[string]string)
+	for _, member := range strings.Split(header, ",") {
+		member, _, _ = strings.Cut(member, ";")
+		key, value, ok := strings.Cut(member, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if key == "" || strings.ContainsAny(key, " \t\"(),/:<=>?@[\\]{}") {
+			continue
+		}
+		value, err := url.PathUnescape(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		baggage[key] = value
+	}
+	if len(baggage) == 0 {
+		return nil
+	}
+	return baggage
+}
+
+type baggageContextKeyT struct{}
+
+// ContextWithBaggage returns a Context that stores baggage.
+func ContextWithBaggage(ctx context.Context, baggage map[string]string) context.Context {
+	return context.WithValue(ctx, baggageContextKeyT{}, baggage)
+}
+
+// BaggageFromContext returns the baggage stored in ctx and whether any
+// was present. A nil ctx holds no baggage.
+func BaggageFromContext(ctx context.Context) (map[string]string, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	baggage, ok := ctx.Value(baggageContextKeyT{}).(map[string]string)
+	return baggage, ok
+}
+
+// NewBaggageExtractor returns an extractor function for the
+// [github.com/veqryn/slog-context] package that logs the given baggage
+// keys found in the context as string attributes. As the values are
+// percent decoded, characters other than printable ASCII are replaced
+// by '?' to keep clients from forging log lines.
+func NewBaggageExtractor(keys ...string) func(ctx context.Context, recordT time.Time, recordLvl slog.Level, recordMsg string) []slog.Attr {
+	// The keys are read by concurrent log calls, the caller may reuse
+	// the slice.
+	keys = slices.Clone(keys)
+	return func(ctx context.Context, recordT time.Time, recordLvl slog.Level, recordMsg string) []slog.Attr {
+		baggage, ok := BaggageFromContext(ctx)
+		if !ok {
+			return nil
+		}
+		var attrs []slog.Attr
+		for _, key := range keys {
+			if value, ok := baggage[key]; ok {
+				attrs = append(attrs, slog.String(key, printable(value)))
+			}
+		}
+		return attrs
+	}
+}
@@ -0,0 +1,59 @@
+package traceparent_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	traceparent "github.com/jum/slog-traceparent"
+)
+
+func TestParseBaggage(t *testing.T) {
+	got := traceparent.ParseBaggage("userId=alice, serverNode=DF%2028;prop=1 , isProduction=false,bad,=empty,bad key=x,enc=%zz,path=%2Fapi%2Fv1")
+	want := map[string]string{
+		"userId":       "alice",
+		"serverNode":   "DF 28",
+		"isProduction": "false",
+		"path":         "/api/v1",
+	}
+	if len(got) != len(want) {
+		t.Errorf("ParseBaggage = %v, want %v", got, want)
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("baggage[%q] = %q, want %q", key, got[key], value)
+		}
+	}
+	for _, header := range []string{"", "bad", ",,", "k=%zz"} {
+		if got := traceparent.ParseBaggage(header); got != nil {
+			t.Errorf("ParseBaggage(%q) = %v, want nil", header, got)
+		}
+	}
+}
+
+func TestBaggage(t *testing.T) {
+	var ctx context.Context
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx = r.Context()
+	})
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("baggage", "userId=alice%20smith,tenant=acme%0A,bad")
+	traceparent.New(next, traceparent.WithBaggage()).ServeHTTP(httptest.NewRecorder(), r)
+
+	baggage, ok := traceparent.BaggageFromContext(ctx)
+	if !ok || baggage["userId"] != "alice smith" || len(baggage) != 2 {
+		t.Fatalf("BaggageFromContext = %v, %v", baggage, ok)
+	}
+	attrs := attrValues(traceparent.NewBaggageExtractor("userId", "tenant", "other")(ctx, time.Now(), slog.LevelInfo, "msg"))
+	if len(attrs) != 2 || attrs["userId"] != "alice smith" || attrs["tenant"] != "acme?" {
+		t.Errorf("attrs = %v", attrs)
+	}
+
+	traceparent.New(next).ServeHTTP(httptest.NewRecorder(), r)
+	if _, ok := traceparent.BaggageFromContext(ctx); ok {
+		t.Error("baggage stored without the option")
+	}
+}
@@ -0,0 +1,138 @@
+package traceparent_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	traceparent "github.com/jum/slog-traceparent"
+)
+
+func BenchmarkNew(b *testing.B) {
+	handler := traceparent.New(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	for _, bm := range []struct {
+		name   string
+		header string
+	}{
+		{"valid", validHeader},
+		{"invalid", "00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01"},
+		{"missing", ""},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if bm.header != "" {
+				r.Header.Set("traceparent", bm.header)
+			}
+			w := httptest.NewRecorder()
+			b.ReportAllocs()
+			for b.Loop() {
+				handler.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+func BenchmarkParseTraceparent(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := traceparent.ParseTraceparent(validHeader); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkParseTraceparentSplit parses the header the way the package
+// did before scanning the fixed offsets, for comparing allocations with
+// BenchmarkParseTraceparent.
+func BenchmarkParseTraceparentSplit(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		parts := strings.Split(validHeader, "-")
+		if len(parts) != 4 {
+			b.Fatal("malformed")
+		}
+		if _, err := traceparent.ParseTraceID(parts[1]); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := traceparent.ParseSpanID(parts[2]); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := traceparent.ParseFlags(parts[3]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseTraceparentOversized(b *testing.B) {
+	header := validHeader + strings.Repeat("-00", 1<<20)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := traceparent.ParseTraceparent(header); err == nil {
+			b.Fatal("oversized header accepted")
+		}
+	}
+}
+
+func BenchmarkNewOversized(b *testing.B) {
+	handler := traceparent.New(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("traceparent", validHeader+strings.Repeat("-00", 1<<20))
+	r.Header.Set("tracestate", strings.Repeat("a=1,", 1<<20))
+	w := httptest.NewRecorder()
+	b.ReportAllocs()
+	for b.Loop() {
+		handler.ServeHTTP(w, r)
+	}
+}
+
+func TestOversizedTracestate(t *testing.T) {
+	_, got, _ := serve(t, nil, http.Header{
+		"traceparent": {validHeader},
+		"tracestate":  {strings.Repeat("a=1,", traceparent.DefaultMaxTracestateLen)},
+	})
+	if !got.Valid() {
+		t.Fatal("traceparent dropped with oversized tracestate")
+	}
+	if got.State != "" || got.StateMembers != nil {
+		t.Errorf("oversized tracestate kept: %d bytes", len(got.State))
+	}
+}
+
+func benchmarkTraces(b *testing.B) []traceparent.Trace {
+	trace, err := traceparent.ParseTraceparent(validHeader)
+	if err != nil {
+		b.Fatal(err)
+	}
+	traces := make([]traceparent.Trace, 100)
+	for i := range traces {
+		traces[i] = trace
+	}
+	return traces
+}
+
+func BenchmarkAttrsForTraces(b *testing.B) {
+	traces := benchmarkTraces(b)
+	b.ReportAllocs()
+	for b.Loop() {
+		traceparent.AttrsForTraces(traces)
+	}
+}
+
+// BenchmarkAttrsForTracesLoop calls the single extractor for every
+// trace, the baseline for BenchmarkAttrsForTraces.
+func BenchmarkAttrsForTracesLoop(b *testing.B) {
+	traces := benchmarkTraces(b)
+	extract := traceparent.NewExtractor()
+	now := time.Now()
+	b.ReportAllocs()
+	for b.Loop() {
+		out := make([][]slog.Attr, len(traces))
+		for i, trace := range traces {
+			out[i] = extract(trace.Context(context.Background()), now, slog.LevelInfo, "")
+		}
+	}
+}
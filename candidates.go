@@ -0,0 +1,51 @@
+package traceparent
+
+import (
+	"net/http"
+	"strings"
+)
+
+// candidate is a header the middleware tries to parse a trace from.
+// A nil parse means a W3C traceparent header.
+type candidate struct {
+	name  string
+	parse func(Carrier) (Trace, error)
+}
+
+// headerParsers maps the lowercase header names of other propagation
+// formats to their parsers.
+var headerParsers = map[string]func(Carrier) (Trace, error){
+	"b3":           ExtractB3,
+	"x-b3-traceid": ExtractB3,
+	"x-amzn-trace-id": func(c Carrier) (Trace, error) {
+		return ParseXRay(c.Get("X-Amzn-Trace-Id"))
+	},
+	"x-datadog-trace-id": ExtractDatadog,
+}
+
+// resolveCandidates returns the headers to try in order, either those
+// given by WithHeaderCandidates or the traceparent header followed by
+// the enabled fallbacks.
+func (cfg *config) resolveCandidates() []candidate {
+	names := cfg.candidateNames
+	if names == nil {
+		names = []string{cfg.headerName}
+		if cfg.b3Fallback {
+			names = append(names, "b3")
+		}
+		if cfg.xrayFallback {
+			names = append(names, "X-Amzn-Trace-Id")
+		}
+		if cfg.datadogFallback {
+			names = append(names, "X-Datadog-Trace-Id")
+		}
+	}
+	candidates := make([]candidate, 0, len(names))
+	for _, name := range names {
+		candidates = append(candidates, candidate{
+			name:  http.CanonicalHeaderKey(name),
+			parse: headerParsers[strings.ToLower(name)],
+		})
+	}
+	return candidates
+}
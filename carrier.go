@@ -0,0 +1,162 @@
+package traceparent
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Carrier is the transport a trace is propagated over, for example the
+// headers of a HTTP request or the metadata of a message.
+type Carrier interface {
+	Get(key string) string
+	Set(key, value string)
+}
+
+// HeaderCarrier adapts [http.Header] to the [Carrier] interface.
+type HeaderCarrier http.Header
+
+// Get implements [Carrier].
+func (c HeaderCarrier) Get(key string) string {
+	return http.Header(c).Get(key)
+}
+
+// Set implements [Carrier].
+func (c HeaderCarrier) Set(key, value string) {
+	http.Header(c).Set(key, value)
+}
+
+// MapCarrier adapts a map to the [Carrier] interface.
+type MapCarrier map[string]string
+
+// Get implements [Carrier].
+func (c MapCarrier) Get(key string) string {
+	return c[key]
+}
+
+// Keys returns the keys stored in the carrier.
+func (c MapCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Set implements [Carrier].
+func (c MapCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+// Extract parses the traceparent and tracestate values of carrier into
+// a [Trace] and reports whether a valid trace was found. Carriers that
+// do not canonicalize their keys like [http.Header] does are matched
+// case-insensitively if they implement a Keys() []string method, as
+// [MapCarrier] does.
+func Extract(carrier Carrier) (Trace, bool) {
+	trace, err := ParseTraceparent(carrierGet(carrier, "traceparent"))
+	if err != nil {
+		return Trace{}, false
+	}
+	return trace.WithState(carrierGet(carrier, "tracestate")), true
+}
+
+// Sources reported by [ExtractWithSource], named after the primary
+// header of each format.
+const (
+	SourceTraceparent = "traceparent"
+	SourceB3          = "b3"
+	SourceXRay        = "x-amzn-trace-id"
+	SourceDatadog     = "x-datadog-trace-id"
+)
+
+// ExtractWithSource is like [Extract] but also tries the B3, X-Ray and
+// Datadog formats in that order, and reports which one supplied the
+// trace, so gateways can strip legacy headers. The source is one of
+// the Source constants, or empty if ok is false.
+func ExtractWithSource(carrier Carrier) (trace Trace, source string, ok bool) {
+	if trace, ok := Extract(carrier); ok {
+		return trace, SourceTraceparent, true
+	}
+	for _, source := range []string{SourceB3, SourceXRay, SourceDatadog} {
+		if trace, err := headerParsers[source](carrier); err == nil {
+			return trace, source, true
+		}
+	}
+	return Trace{}, "", false
+}
+
+// carrierGet returns the value of key in carrier, falling back to a
+// case-insensitive match on the keys of carrier.
+func carrierGet(carrier Carrier, key string) string {
+	if value := carrier.Get(key); value != "" {
+		return value
+	}
+	if _, ok := carrier.(HeaderCarrier); ok {
+		return ""
+	}
+	keyed, ok := carrier.(interface{ Keys() []string })
+	if !ok {
+		return ""
+	}
+	for _, k := range keyed.Keys() {
+		if strings.EqualFold(k, key) {
+			return carrier.Get(k)
+		}
+	}
+	return ""
+}
+
+// Inject sets the traceparent and tracestate values of carrier from
+// trace. Nothing is set for a Trace without trace-id, see
+// [Trace.Header].
+func Inject(trace Trace, carrier Carrier) {
+	header := trace.Header()
+	if header == "" {
+		return
+	}
+	carrier.Set("traceparent", header)
+	if trace.State != "" {
+		carrier.Set("tracestate", trace.State)
+	}
+}
+
+// BytesMapCarrier adapts a map of byte slices, like the headers of
+// Kafka or NATS messages, to the [Carrier] interface.
+type BytesMapCarrier map[string][]byte
+
+// Get implements [Carrier].
+func (c BytesMapCarrier) Get(key string) string {
+	return string(c[key])
+}
+
+// Keys returns the keys stored in the carrier.
+func (c BytesMapCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Set implements [Carrier].
+func (c BytesMapCarrier) Set(key, value string) {
+	c[key] = []byte(value)
+}
+
+// InjectBytes returns the header key and value to propagate trace in
+// messaging systems with byte slice headers. The value is nil for a
+// Trace without trace-id, see [Trace.Header].
+func InjectBytes(trace Trace) (key string, value []byte) {
+	header := trace.Header()
+	if header == "" {
+		return "traceparent", nil
+	}
+	return "traceparent", []byte(header)
+}
+
+// ExtractBytes parses a traceparent header value received as a byte
+// slice and reports whether it held a valid trace.
+func ExtractBytes(value []byte) (Trace, bool) {
+	trace, err := ParseTraceparent(string(value))
+	return trace, err == nil
+}
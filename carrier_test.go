@@ -0,0 +1,98 @@
+package traceparent_test
+
+import (
+	"net/http"
+	"testing"
+
+	traceparent "github.com/jum/slog-traceparent"
+)
+
+func TestCarriers(t *testing.T) {
+	trace, err := traceparent.ParseTraceparent(validHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trace = trace.WithState("congo=t61rcWkgMzE")
+	for name, carrier := range map[string]traceparent.Carrier{
+		"header": traceparent.HeaderCarrier(http.Header{}),
+		"map":    traceparent.MapCarrier{},
+		"bytes":  traceparent.BytesMapCarrier{},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, ok := traceparent.Extract(carrier); ok {
+				t.Error("Extract found a trace in an empty carrier")
+			}
+			traceparent.Inject(trace, carrier)
+			if carrier.Get("traceparent") != validHeader || carrier.Get("tracestate") != trace.State {
+				t.Errorf("Inject set %q and %q", carrier.Get("traceparent"), carrier.Get("tracestate"))
+			}
+			got, ok := traceparent.Extract(carrier)
+			if !ok || !got.Equal(trace) || got.State != trace.State {
+				t.Errorf("Extract = %v, %v, want %v", got, ok, trace)
+			}
+			carrier.Set("traceparent", "00-xyz")
+			if _, ok := traceparent.Extract(carrier); ok {
+				t.Error("Extract accepted a malformed traceparent")
+			}
+		})
+	}
+	header := http.Header{}
+	traceparent.Inject(trace, traceparent.HeaderCarrier(header))
+	if header.Get("Traceparent") != validHeader {
+		t.Errorf("http.Header = %v", header)
+	}
+	carrier := traceparent.MapCarrier{}
+	traceparent.Inject(traceparent.Trace{}, carrier)
+	if len(carrier) != 0 {
+		t.Errorf("Inject of the zero Trace set %v", carrier)
+	}
+}
+
+func TestExtractCaseInsensitive(t *testing.T) {
+	for _, carrier := range []traceparent.Carrier{
+		traceparent.MapCarrier{"TRACEPARENT": validHeader, "TraceState": "congo=t61rcWkgMzE"},
+		traceparent.BytesMapCarrier{"Traceparent": []byte(validHeader), "TRACESTATE": []byte("congo=t61rcWkgMzE")},
+	} {
+		got, ok := traceparent.Extract(carrier)
+		if !ok || got.Header() != validHeader || got.State != "congo=t61rcWkgMzE" {
+			t.Errorf("Extract(%v) = %v, %v", carrier, got, ok)
+		}
+	}
+	// http.Header canonicalizes on Set, keys stored otherwise are not
+	// matched, as with http.Header.Get.
+	header := traceparent.HeaderCarrier(http.Header{"TRACEPARENT": {validHeader}})
+	if got, ok := traceparent.Extract(header); ok {
+		t.Errorf("Extract of a non-canonical http.Header = %v", got)
+	}
+}
+
+func TestExtractWithSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		carrier traceparent.MapCarrier
+		source  string
+	}{
+		{"traceparent", traceparent.MapCarrier{"traceparent": validHeader, "b3": traceID + "-" + spanID + "-1"}, traceparent.SourceTraceparent},
+		{"b3 only", traceparent.MapCarrier{"b3": traceID + "-" + spanID + "-1"}, traceparent.SourceB3},
+		{"b3 multi", traceparent.MapCarrier{"X-B3-TraceId": traceID, "X-B3-SpanId": spanID, "X-B3-Sampled": "1"}, traceparent.SourceB3},
+		{"x-ray", traceparent.MapCarrier{"X-Amzn-Trace-Id": xrayHeader}, traceparent.SourceXRay},
+		{"datadog", traceparent.MapCarrier{
+			"x-datadog-trace-id":          "11803532876627986230",
+			"x-datadog-parent-id":         "67667974448284343",
+			"x-datadog-sampling-priority": "1",
+			"x-datadog-tags":              "_dd.p.tid=4bf92f3577b34da6",
+		}, traceparent.SourceDatadog},
+		{"invalid traceparent", traceparent.MapCarrier{"traceparent": "00-xyz", "b3": traceID + "-" + spanID + "-1"}, traceparent.SourceB3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trace, source, ok := traceparent.ExtractWithSource(tt.carrier)
+			if !ok || source != tt.source || trace.Header() != validHeader {
+				t.Errorf("ExtractWithSource = %v, %q, %v, want %q", trace, source, ok, tt.source)
+			}
+		})
+	}
+	if trace, source, ok := traceparent.ExtractWithSource(traceparent.MapCarrier{"other": "x"}); ok || source != "" {
+		t.Errorf("ExtractWithSource = %v, %q, %v, want nothing", trace, source, ok)
+	}
+}
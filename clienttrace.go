@@ -0,0 +1,41 @@
+package traceparent
+
+import (
+	"context"
+	"crypto/tls"
+	"log/slog"
+	"net/http/httptrace"
+)
+
+// NewClientTrace returns a [httptrace.ClientTrace] logging the
+// connection events of an outgoing request at debug level to logger,
+// with the attributes of the Trace stored in ctx bound to the logger by
+// [LoggerFor], so the events are logged without a context. Install it
+// with [httptrace.WithClientTrace]:
+//
+//	ctx = httptrace.WithClientTrace(ctx, traceparent.NewClientTrace(ctx, logger))
+func NewClientTrace(ctx context.Context, logger *slog.Logger) *httptrace.ClientTrace {
+	logger = LoggerFor(ctx, logger)
+	return &httptrace.ClientTrace{
+		GetConn: func(hostPort string) {
+			logger.Debug("httptrace: get conn", slog.String("hostPort", hostPort))
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			logger.Debug("httptrace: got conn",
+				slog.String("remote", info.Conn.RemoteAddr().String()),
+				slog.Bool("reused", info.Reused),
+				slog.Bool("wasIdle", info.WasIdle))
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			logger.Debug("httptrace: dns done", slog.Any("addrs", info.Addrs), slog.Any("err", info.Err))
+		},
+		ConnectDone: func(network, addr string, err error) {
+			logger.Debug("httptrace: connect done",
+				slog.String("network", network), slog.String("addr", addr), slog.Any("err", err))
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			logger.Debug("httptrace: tls handshake done",
+				slog.String("version", tls.VersionName(state.Version)), slog.Any("err", err))
+		},
+	}
+}
@@ -0,0 +1,96 @@
+package traceparent_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	traceparent "github.com/jum/slog-traceparent"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// TestConcurrentUse hammers a single middleware, extractor and
+// Transport from many goroutines with varied headers, run it with
+// -race to detect shared mutable state.
+func TestConcurrentUse(t *testing.T) {
+	logger := slog.New(&traceparent.Handler{Next: slog.NewJSONHandler(io.Discard, nil)})
+	extract := traceparent.NewExtractor(traceparent.WithGroup("trace"), traceparent.WithElapsed(),
+		traceparent.WithTracestateMembers("a"), traceparent.WithFlagsKey("flags"))
+	baggage := traceparent.NewBaggageExtractor("user")
+	transport := &traceparent.Transport{
+		Base: roundTripFunc(func(*http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		}),
+		TracestateAllowlist: []string{"a"},
+		TracestateVendor:    "a",
+		Datadog:             true,
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger.InfoContext(ctx, "handled")
+		extract(ctx, time.Now(), slog.LevelInfo, "handled")
+		baggage(ctx, time.Now(), slog.LevelInfo, "handled")
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com/", nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := traceparent.New(next,
+		traceparent.WithGenerateMissing(),
+		traceparent.WithSampleRatio(0.5),
+		traceparent.WithSampleSource(rand.NewPCG(1, 2)),
+		traceparent.WithChildSpan(),
+		traceparent.WithB3Fallback(),
+		traceparent.WithXRayFallback(),
+		traceparent.WithDatadogFallback(),
+		traceparent.WithStartTime(),
+		traceparent.WithBaggage(),
+		traceparent.WithRequestIDHeader("X-Request-ID"),
+		traceparent.WithMetrics(func(string) {}),
+		traceparent.WithSampler(func(r *http.Request, trace traceparent.Trace) bool { return trace.Sampled }),
+		traceparent.WithTraceResponse(),
+		traceparent.WithServerTiming(),
+		traceparent.WithResponseLogging(logger),
+		traceparent.WithLatencyObserver(func(bool, time.Duration) {}),
+		traceparent.WithSkipPaths("/skip"),
+	)
+	headers := []http.Header{
+		{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}, "Tracestate": {"a=1,b=2"}},
+		{"Traceparent": {"garbage"}, "Baggage": {"user=alice"}},
+		{"B3": {"80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1"}},
+		{"X-Amzn-Trace-Id": {"Root=1-5759e988-bd862e3fe1be46a994272793"}},
+		{"X-Request-Id": {"req-1"}},
+		{},
+	}
+	paths := []string{"/", "/skip"}
+	var wg sync.WaitGroup
+	for i := range 64 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 100 {
+				r := httptest.NewRequest(http.MethodGet, paths[j%len(paths)], nil)
+				r.Header = headers[(i+j)%len(headers)].Clone()
+				handler.ServeHTTP(httptest.NewRecorder(), r)
+				traceparent.TraceParentExtractor(context.Background(), time.Now(), slog.LevelInfo, "")
+			}
+		}()
+	}
+	wg.Wait()
+}
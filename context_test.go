@@ -0,0 +1,63 @@
+package traceparent_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	traceparent "github.com/jum/slog-traceparent"
+)
+
+func TestFromContext(t *testing.T) {
+	if _, ok := traceparent.FromContext(context.Background()); ok {
+		t.Error("FromContext found a trace in an empty context")
+	}
+	var nilCtx context.Context
+	if _, ok := traceparent.FromContext(nilCtx); ok {
+		t.Error("FromContext found a trace in a nil context")
+	}
+	want, err := traceparent.ParseTraceparent(validHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := traceparent.FromContext(want.Context(context.Background()))
+	if !ok || !got.Equal(want) {
+		t.Errorf("FromContext = %v, %v, want %v", got, ok, want)
+	}
+}
+
+func TestContextKeys(t *testing.T) {
+	inbound, forged := traceparent.NewContextKey("inbound"), traceparent.NewContextKey("forged")
+	var ctx context.Context
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx = r.Context()
+	})
+	handler := traceparent.New(
+		traceparent.New(next, traceparent.WithContextKey(forged), traceparent.WithHeaderName("x-forged")),
+		traceparent.WithContextKey(inbound),
+	)
+	forgedHeader := "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-00"
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("traceparent", validHeader)
+	r.Header.Set("x-forged", forgedHeader)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	for key, want := range map[any]string{inbound: validHeader, forged: forgedHeader} {
+		if got, ok := traceparent.FromContextWithKey(ctx, key); !ok || got.Header() != want {
+			t.Errorf("trace under %v = %v, want %s", key, got, want)
+		}
+		attrs := attrValues(traceparent.NewExtractor(traceparent.WithExtractorContextKey(key))(ctx, time.Now(), slog.LevelInfo, ""))
+		if attrs["traceID"] != want[3:35] {
+			t.Errorf("extractor for %v logged %v", key, attrs)
+		}
+	}
+	if _, ok := traceparent.FromContext(ctx); ok {
+		t.Error("trace stored under the default key")
+	}
+	if traceparent.NewContextKey("inbound") != inbound || traceparent.ContextKey() == inbound {
+		t.Error("context keys do not compare by name")
+	}
+}
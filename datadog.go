@@ -0,0 +1,71 @@
+package traceparent
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ExtractDatadog parses the Datadog x-datadog-trace-id,
+// x-datadog-parent-id and x-datadog-sampling-priority values of
+// carrier into a [Trace]. The decimal 64 bit trace-id is converted
+// into the 128 bit W3C form, using the high bits from the _dd.p.tid
+// tag of x-datadog-tags when present.
+func ExtractDatadog(carrier Carrier) (Trace, error) {
+	low, err := strconv.ParseUint(carrier.Get("x-datadog-trace-id"), 10, 64)
+	if err != nil {
+		return Trace{}, fmt.Errorf("%w: invalid datadog trace-id", ErrMalformed)
+	}
+	var trace Trace
+	binary.BigEndian.PutUint64(trace.ID[8:], low)
+	for _, tag := range strings.Split(carrier.Get("x-datadog-tags"), ",") {
+		if value, ok := strings.CutPrefix(tag, "_dd.p.tid="); ok {
+			if len(value) != 16 || !decodeLowerHex(trace.ID[:8], value) {
+				return Trace{}, fmt.Errorf("%w: invalid datadog _dd.p.tid", ErrMalformed)
+			}
+		}
+	}
+	if trace.ID.IsZero() {
+		return Trace{}, fmt.Errorf("%w: trace-id", ErrZeroID)
+	}
+	if parent := carrier.Get("x-datadog-parent-id"); parent != "" {
+		span, err := strconv.ParseUint(parent, 10, 64)
+		if err != nil {
+			return Trace{}, fmt.Errorf("%w: invalid datadog parent-id", ErrMalformed)
+		}
+		binary.BigEndian.PutUint64(trace.SpanID[:], span)
+	}
+	if priority := carrier.Get("x-datadog-sampling-priority"); priority != "" {
+		p, err := strconv.Atoi(priority)
+		if err != nil {
+			return Trace{}, fmt.Errorf("%w: datadog sampling priority %q", ErrInvalidFlags, priority)
+		}
+		if p > 0 {
+			trace.Flags = FlagSampled
+			trace.Sampled = true
+		}
+	}
+	return trace, nil
+}
+
+// InjectDatadog sets the Datadog x-datadog-* values of carrier from
+// trace. The high 64 bits of the trace-id are sent as the _dd.p.tid
+// tag in x-datadog-tags, replacing any tags already set.
+func InjectDatadog(trace Trace, carrier Carrier) {
+	if trace.ID.IsZero() {
+		return
+	}
+	carrier.Set("x-datadog-trace-id", strconv.FormatUint(binary.BigEndian.Uint64(trace.ID[8:]), 10))
+	if high := binary.BigEndian.Uint64(trace.ID[:8]); high != 0 {
+		carrier.Set("x-datadog-tags", "_dd.p.tid="+trace.ID.String()[:16])
+	}
+	if !trace.SpanID.IsZero() {
+		carrier.Set("x-datadog-parent-id", strconv.FormatUint(binary.BigEndian.Uint64(trace.SpanID[:]), 10))
+	}
+	priority := "0"
+	if trace.Sampled {
+		priority = "1"
+	}
+	carrier.Set("x-datadog-sampling-priority", priority)
+}
@@ -0,0 +1,120 @@
+package traceparent_test
+
+import (
+	"net/http"
+	"testing"
+
+	traceparent "github.com/jum/slog-traceparent"
+)
+
+func TestExtractDatadog(t *testing.T) {
+	tests := []struct {
+		name    string
+		carrier traceparent.MapCarrier
+		want    string
+	}{
+		{"64 bit", traceparent.MapCarrier{
+			"x-datadog-trace-id":          "11803532876627986230",
+			"x-datadog-parent-id":         "67667974448284343",
+			"x-datadog-sampling-priority": "1",
+		}, "00-0000000000000000a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{"128 bit", traceparent.MapCarrier{
+			"x-datadog-trace-id":          "11803532876627986230",
+			"x-datadog-parent-id":         "67667974448284343",
+			"x-datadog-sampling-priority": "2",
+			"x-datadog-tags":              "_dd.p.dm=-4,_dd.p.tid=4bf92f3577b34da6",
+		}, validHeader},
+		{"small ids", traceparent.MapCarrier{
+			"x-datadog-trace-id":          "1",
+			"x-datadog-parent-id":         "2",
+			"x-datadog-sampling-priority": "0",
+		}, "00-00000000000000000000000000000001-0000000000000002-00"},
+		{"max ids", traceparent.MapCarrier{
+			"x-datadog-trace-id":          "18446744073709551615",
+			"x-datadog-parent-id":         "18446744073709551615",
+			"x-datadog-sampling-priority": "-1",
+		}, "00-0000000000000000ffffffffffffffff-ffffffffffffffff-00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trace, err := traceparent.ExtractDatadog(tt.carrier)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := trace.Header(); got != tt.want {
+				t.Errorf("Header() = %q, want %q", got, tt.want)
+			}
+			carrier := traceparent.MapCarrier{}
+			traceparent.InjectDatadog(trace, carrier)
+			again, err := traceparent.ExtractDatadog(carrier)
+			if err != nil || !again.Equal(trace) {
+				t.Errorf("round trip through %v = %v, %v", carrier, again, err)
+			}
+		})
+	}
+}
+
+func TestExtractDatadogInvalid(t *testing.T) {
+	for _, carrier := range []traceparent.MapCarrier{
+		{},
+		{"x-datadog-trace-id": "0"},
+		{"x-datadog-trace-id": "-1"},
+		{"x-datadog-trace-id": "18446744073709551616"},
+		{"x-datadog-trace-id": "a3ce929d0e0e4736"},
+		{"x-datadog-trace-id": "1", "x-datadog-parent-id": "x"},
+		{"x-datadog-trace-id": "1", "x-datadog-sampling-priority": "yes"},
+		{"x-datadog-trace-id": "1", "x-datadog-tags": "_dd.p.tid=xyz"},
+	} {
+		if trace, err := traceparent.ExtractDatadog(carrier); err == nil {
+			t.Errorf("ExtractDatadog(%v) = %v, want an error", carrier, trace)
+		}
+	}
+}
+
+func TestDatadogFallback(t *testing.T) {
+	opts := []traceparent.Option{traceparent.WithDatadogFallback()}
+	header := http.Header{
+		"X-Datadog-Trace-Id":          {"11803532876627986230"},
+		"X-Datadog-Parent-Id":         {"67667974448284343"},
+		"X-Datadog-Sampling-Priority": {"1"},
+	}
+	_, got, _ := serve(t, opts, header)
+	if got.Header() != "00-0000000000000000a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Errorf("trace %v, want the datadog trace", got)
+	}
+	header.Set("traceparent", validHeader)
+	if _, got, _ = serve(t, opts, header); got.Header() != validHeader {
+		t.Errorf("trace %v, want the traceparent %s", got, validHeader)
+	}
+}
+
+func TestTransportDatadog(t *testing.T) {
+	var sent http.Header
+	transport := &traceparent.Transport{
+		Base: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			sent = r.Header
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		}),
+		Datadog: true,
+	}
+	trace, err := traceparent.ParseTraceparent(validHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequestWithContext(trace.Context(t.Context()), http.MethodGet, "http://example.com/", nil)
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"traceparent":                 validHeader,
+		"x-datadog-trace-id":          "11803532876627986230",
+		"x-datadog-parent-id":         "67667974448284343",
+		"x-datadog-sampling-priority": "1",
+		"x-datadog-tags":              "_dd.p.tid=4bf92f3577b34da6",
+	}
+	for key, value := range want {
+		if got := sent.Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
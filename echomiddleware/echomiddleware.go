@@ -0,0 +1,93 @@
+// Package echomiddleware adapts the [traceparent.New] middleware to
+// the Echo framework. It lives in its own package to keep the Echo
+// dependency optional.
+package echomiddleware
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	traceparent "github.com/jum/slog-traceparent"
+	"github.com/labstack/echo/v4"
+)
+
+type echoContextKeyT struct{}
+
+// request carries the Echo context and the next handler through the
+// core middleware.
+type request struct {
+	c    echo.Context
+	next echo.HandlerFunc
+}
+
+// Middleware returns an [echo.MiddlewareFunc] that injects the
+// [traceparent.Trace] into the request context, configured by the same
+// opts as [traceparent.New], so context-aware logging inside Echo
+// handlers includes the trace. The remaining handlers write their
+// response through the core middleware, so options like
+// [traceparent.WithResponseLogging] see it. For the same reason an error
+// returned by them is passed to [echo.Context.Error] right away, like
+// the logger middleware of Echo does, and not returned.
+func Middleware(opts ...traceparent.Option) echo.MiddlewareFunc {
+	h := traceparent.New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := r.Context().Value(echoContextKeyT{}).(*request)
+		req.c.SetRequest(r)
+		resp := req.c.Response()
+		req.c.SetResponse(echo.NewResponse(w, req.c.Echo()))
+		defer req.c.SetResponse(resp)
+		if err := req.next(req.c); err != nil {
+			req.c.Error(err)
+		}
+	}), opts...)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := &request{c: c, next: next}
+			r := c.Request()
+			h.ServeHTTP(coreWriter{c.Response()}, r.WithContext(context.WithValue(r.Context(), echoContextKeyT{}, req)))
+			return nil
+		}
+	}
+}
+
+// coreWriter is the Echo response as passed to the core middleware. It
+// hides the Unwrap method of the response, which
+// [traceparent.WithRequireOutermost] takes for a preceding middleware,
+// and forwards the methods of [http.ResponseController] instead.
+type coreWriter struct {
+	r *echo.Response
+}
+
+func (w coreWriter) Header() http.Header {
+	return w.r.Header()
+}
+
+func (w coreWriter) WriteHeader(status int) {
+	w.r.WriteHeader(status)
+}
+
+func (w coreWriter) Write(b []byte) (int, error) {
+	return w.r.Write(b)
+}
+
+func (w coreWriter) Flush() {
+	w.r.Flush()
+}
+
+func (w coreWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.r.Hijack()
+}
+
+func (w coreWriter) SetReadDeadline(deadline time.Time) error {
+	return http.NewResponseController(w.r).SetReadDeadline(deadline)
+}
+
+func (w coreWriter) SetWriteDeadline(deadline time.Time) error {
+	return http.NewResponseController(w.r).SetWriteDeadline(deadline)
+}
+
+func (w coreWriter) EnableFullDuplex() error {
+	return http.NewResponseController(w.r).EnableFullDuplex()
+}
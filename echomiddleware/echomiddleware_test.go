@@ -0,0 +1,100 @@
+package echomiddleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	traceparent "github.com/jum/slog-traceparent"
+	"github.com/jum/slog-traceparent/echomiddleware"
+	"github.com/labstack/echo/v4"
+)
+
+const header = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func decode(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	for _, data := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var line map[string]any
+		if err := json.Unmarshal([]byte(data), &line); err != nil {
+			t.Fatalf("log %q: %v", data, err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestMiddleware(t *testing.T) {
+	var logs, warnings bytes.Buffer
+	logger := slog.New(&traceparent.Handler{Next: slog.NewJSONHandler(&logs, nil)})
+	e := echo.New()
+	e.Use(echomiddleware.Middleware(
+		traceparent.WithResponseLogging(logger),
+		traceparent.WithTraceResponse(),
+		traceparent.WithRequireOutermost(),
+		traceparent.WithErrorLogger(slog.New(slog.NewTextHandler(&warnings, nil))),
+	))
+	e.GET("/tea", func(c echo.Context) error {
+		logger.InfoContext(c.Request().Context(), "brewing")
+		return c.String(http.StatusTeapot, "short")
+	})
+	e.GET("/fail", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusConflict, "conflict")
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/tea", nil)
+	r.Header.Set("traceparent", header)
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, r)
+	if w.Code != http.StatusTeapot || w.Body.String() != "short" {
+		t.Errorf("response %d %q", w.Code, w.Body)
+	}
+	if w.Header().Get("traceresponse") != header {
+		t.Errorf("traceresponse %q", w.Header().Get("traceresponse"))
+	}
+	lines := decode(t, &logs)
+	if len(lines) != 2 {
+		t.Fatalf("got %d log lines, want 2", len(lines))
+	}
+	if lines[0]["msg"] != "brewing" || lines[0]["traceID"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("handler log line %v", lines[0])
+	}
+	if lines[1]["status"] != float64(http.StatusTeapot) || lines[1]["path"] != "/tea" {
+		t.Errorf("response log line %v", lines[1])
+	}
+	if warnings.Len() != 0 {
+		t.Errorf("unexpected warning %q", warnings.String())
+	}
+
+	logs.Reset()
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fail", nil))
+	if w.Code != http.StatusConflict {
+		t.Errorf("error response %d", w.Code)
+	}
+	if lines := decode(t, &logs); lines[0]["status"] != float64(http.StatusConflict) {
+		t.Errorf("error log line %v", lines[0])
+	}
+}
+
+func TestMiddlewareGate(t *testing.T) {
+	e := echo.New()
+	e.Use(echomiddleware.Middleware(traceparent.WithGate(func(trace traceparent.Trace) (bool, int) {
+		return trace.Valid(), http.StatusForbidden
+	})))
+	called := false
+	e.GET("/", func(c echo.Context) error {
+		called = true
+		return nil
+	})
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if called || w.Code != http.StatusForbidden {
+		t.Errorf("status %d called %v, want 403 and not called", w.Code, called)
+	}
+}
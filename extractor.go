@@ -0,0 +1,357 @@
+package traceparent
+
+import (
+	"context"
+	"encoding/hex"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// ExtractorOption configures the extractor returned by [NewExtractor].
+type ExtractorOption func(*extractorConfig)
+
+type extractorConfig struct {
+	contextKey      any
+	traceIDKey      string
+	spanIDKey       string
+	sampledKey      string
+	group           string
+	elapsed         bool
+	parentKey       string
+	flagsKey        string
+	absentID        string
+	minLevel        slog.Leveler
+	prefix          string
+	markMissingSpan bool
+	omitSampled     bool
+	stateKeys       []string
+	headerKey       string
+	sampledFormat   func(bool) slog.Value
+}
+
+// WithTraceIDKey sets the attribute key for the trace-id, the default
+// is "traceID".
+func WithTraceIDKey(key string) ExtractorOption {
+	return func(cfg *extractorConfig) {
+		cfg.traceIDKey = key
+	}
+}
+
+// WithSpanIDKey sets the attribute key for the span-id, the default is
+// "spanID".
+func WithSpanIDKey(key string) ExtractorOption {
+	return func(cfg *extractorConfig) {
+		cfg.spanIDKey = key
+	}
+}
+
+// WithSampledKey sets the attribute key for the sampled flag, the
+// default is "traceSampled".
+func WithSampledKey(key string) ExtractorOption {
+	return func(cfg *extractorConfig) {
+		cfg.sampledKey = key
+	}
+}
+
+// WithSampledFormat sets the function creating the value of the
+// sampled attribute, for log schemas requiring for example "1" and "0"
+// strings. The default is [slog.BoolValue].
+func WithSampledFormat(format func(sampled bool) slog.Value) ExtractorOption {
+	return func(cfg *extractorConfig) {
+		cfg.sampledFormat = format
+	}
+}
+
+// WithoutSampled makes the extractor omit the sampled attribute, for
+// backends that make their own sampling decision.
+func WithoutSampled() ExtractorOption {
+	return func(cfg *extractorConfig) {
+		cfg.omitSampled = true
+	}
+}
+
+// WithParentSpanIDKey makes the extractor emit the ParentSpanID of
+// the trace under key, if it has one.
+func WithParentSpanIDKey(key string) ExtractorOption {
+	return func(cfg *extractorConfig) {
+		cfg.parentKey = key
+	}
+}
+
+// WithFlagsKey makes the extractor emit the effective trace flags, see
+// [Trace.EffectiveFlags], as two hex digits under key.
+func WithFlagsKey(key string) ExtractorOption {
+	return func(cfg *extractorConfig) {
+		cfg.flagsKey = key
+	}
+}
+
+// WithRawTraceparentKey makes the extractor emit the trace serialized
+// as a traceparent header under key, see [Trace.Header], for backends
+// that re-parse it. Traces without a span-id have no such attribute.
+func WithRawTraceparentKey(key string) ExtractorOption {
+	return func(cfg *extractorConfig) {
+		cfg.headerKey = key
+	}
+}
+
+// WithTracestateMembers makes the extractor emit the values of the
+// tracestate list members with the given keys in a tracestate group.
+// Percent-encoded characters in the values are decoded for logging,
+// invalid sequences are kept as they are and decoded characters other
+// than printable ASCII are replaced by '?'. The tracestate propagated is
+// not changed.
+func WithTracestateMembers(keys ...string) ExtractorOption {
+	return func(cfg *extractorConfig) {
+		cfg.stateKeys = slices.Clone(keys)
+	}
+}
+
+// WithAbsentTraceID makes the extractor emit value as the trace-id if
+// the context holds no valid trace, so log queries can rely on the
+// attribute being present.
+func WithAbsentTraceID(value string) ExtractorOption {
+	return func(cfg *extractorConfig) {
+		cfg.absentID = value
+	}
+}
+
+// WithMinLevel makes the extractor return no attributes for records
+// below level, so hot debug logging is not burdened with the trace.
+// A [*slog.LevelVar] may be passed to change the threshold at runtime.
+// By default records of all levels get the attributes.
+func WithMinLevel(level slog.Leveler) ExtractorOption {
+	return func(cfg *extractorConfig) {
+		cfg.minLevel = level
+	}
+}
+
+// WithSpanMissingMarker makes the extractor emit spanMissing=true in
+// place of the span-id for traces without one, like those taken from
+// upstreams sending a trace-id only.
+func WithSpanMissingMarker() ExtractorOption {
+	return func(cfg *extractorConfig) {
+		cfg.markMissingSpan = true
+	}
+}
+
+// WithAttrPrefix prepends prefix to the key of every attribute the
+// extractor emits, for log schemas like "otel.traceID". Inside a group
+// set by [WithGroup] the keys of the group members are prefixed.
+func WithAttrPrefix(prefix string) ExtractorOption {
+	return func(cfg *extractorConfig) {
+		cfg.prefix = prefix
+	}
+}
+
+// WithGroup makes the extractor return a single group attribute with
+// the given name holding the trace attributes. Inside the group the
+// attribute keys default to "id", "span" and "sampled".
+func WithGroup(name string) ExtractorOption {
+	return func(cfg *extractorConfig) {
+		cfg.group = name
+	}
+}
+
+// WithElapsed makes the extractor emit an elapsedMs attribute with the
+// milliseconds between the request start stored by [WithStartTime]
+// and the time of the log record.
+func WithElapsed() ExtractorOption {
+	return func(cfg *extractorConfig) {
+		cfg.elapsed = true
+	}
+}
+
+// WithExtractorContextKey makes the extractor read the [Trace] stored
+// under key, matching [WithContextKey] on the middleware.
+func WithExtractorContextKey(key any) ExtractorOption {
+	return func(cfg *extractorConfig) {
+		cfg.contextKey = key
+	}
+}
+
+// NewExtractor returns a function suitable for use as an extractor
+// function for the [github.com/veqryn/slog-context] package, configured
+// by opts. The attributes are always returned in the order trace-id,
+// span-id, parent span-id, sampled, flags, whether the extractor is
+// registered to prepend or append.
+func NewExtractor(opts ...ExtractorOption) func(ctx context.Context, recordT time.Time, recordLvl slog.Level, recordMsg string) []slog.Attr {
+	return newExtractorConfig(opts).extract
+}
+
+func newExtractorConfig(opts []ExtractorOption) *extractorConfig {
+	cfg := &extractorConfig{
+		contextKey: traceContextKeyT{},
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	defaults := [3]string{"traceID", "spanID", "traceSampled"}
+	if cfg.group != "" {
+		defaults = [3]string{"id", "span", "sampled"}
+	}
+	for i, key := range []*string{&cfg.traceIDKey, &cfg.spanIDKey, &cfg.sampledKey} {
+		if *key == "" {
+			*key = defaults[i]
+		}
+	}
+	for _, key := range []*string{&cfg.traceIDKey, &cfg.spanIDKey, &cfg.sampledKey, &cfg.parentKey, &cfg.flagsKey, &cfg.headerKey} {
+		if *key != "" {
+			*key = cfg.prefix + *key
+		}
+	}
+	return cfg
+}
+
+func (cfg *extractorConfig) extract(ctx context.Context, recordT time.Time, recordLvl slog.Level, recordMsg string) []slog.Attr {
+	// Logging must never panic, even if passed a nil context.
+	if ctx == nil {
+		return nil
+	}
+	if cfg.minLevel != nil && recordLvl < cfg.minLevel.Level() {
+		return nil
+	}
+	var attrs []slog.Attr
+	if trace, ok := FromContextWithKey(ctx, cfg.contextKey); ok && trace.Valid() {
+		attrs = cfg.traceAttrs(trace)
+	} else if cfg.absentID != "" {
+		attrs = cfg.groupAttrs([]slog.Attr{slog.String(cfg.traceIDKey, cfg.absentID)})
+	}
+	if id, ok := RequestIDFromContext(ctx); ok {
+		attrs = append(attrs, slog.String(cfg.prefix+"requestID", id))
+	}
+	if cfg.elapsed {
+		if start, ok := StartTimeFromContext(ctx); ok {
+			attrs = append(attrs, slog.Int64(cfg.prefix+"elapsedMs", recordT.Sub(start).Milliseconds()))
+		}
+	}
+	return attrs
+}
+
+// traceAttrs returns the attributes for trace.
+func (cfg *extractorConfig) traceAttrs(trace Trace) []slog.Attr {
+	return cfg.groupAttrs(cfg.appendTraceAttrs(make([]slog.Attr, 0, 5), trace))
+}
+
+// appendTraceAttrs appends the attributes for trace to attrs, without
+// the group set by WithGroup.
+func (cfg *extractorConfig) appendTraceAttrs(attrs []slog.Attr, trace Trace) []slog.Attr {
+	attrs = append(attrs, slog.String(cfg.traceIDKey, trace.ID.String()))
+	if !trace.SpanID.IsZero() {
+		attrs = append(attrs, slog.String(cfg.spanIDKey, trace.SpanID.String()))
+	} else if cfg.markMissingSpan {
+		attrs = append(attrs, slog.Bool(cfg.prefix+"spanMissing", true))
+	}
+	if trace.SpanSynthesized {
+		attrs = append(attrs, slog.Bool(cfg.prefix+"spanSynthesized", true))
+	}
+	if cfg.parentKey != "" && !trace.ParentSpanID.IsZero() {
+		attrs = append(attrs, slog.String(cfg.parentKey, trace.ParentSpanID.String()))
+	}
+	if !cfg.omitSampled {
+		if cfg.sampledFormat != nil {
+			attrs = append(attrs, slog.Attr{Key: cfg.sampledKey, Value: cfg.sampledFormat(trace.Sampled)})
+		} else {
+			attrs = append(attrs, slog.Bool(cfg.sampledKey, trace.Sampled))
+		}
+	}
+	if cfg.flagsKey != "" {
+		attrs = append(attrs, slog.String(cfg.flagsKey, hex.EncodeToString([]byte{trace.EffectiveFlags()})))
+	}
+	if cfg.headerKey != "" && !trace.SpanID.IsZero() {
+		attrs = append(attrs, slog.String(cfg.headerKey, trace.Header()))
+	}
+	if trace.StateTruncated {
+		attrs = append(attrs, slog.Bool(cfg.prefix+"tracestateTruncated", true))
+	}
+	if len(cfg.stateKeys) > 0 {
+		var members []slog.Attr
+		for _, key := range cfg.stateKeys {
+			if value, ok := trace.StateMembers[key]; ok {
+				members = append(members, slog.String(key, printable(percentDecode(value))))
+			}
+		}
+		if len(members) > 0 {
+			attrs = append(attrs, slog.Attr{Key: cfg.prefix + "tracestate", Value: slog.GroupValue(members...)})
+		}
+	}
+	return attrs
+}
+
+// groupAttrs wraps attrs in a group if configured by WithGroup.
+func (cfg *extractorConfig) groupAttrs(attrs []slog.Attr) []slog.Attr {
+	if cfg.group != "" {
+		return []slog.Attr{{Key: cfg.group, Value: slog.GroupValue(attrs...)}}
+	}
+	return attrs
+}
+
+// AttrsForTraces returns the attributes the extractor configured by
+// opts emits for each of traces, for batch logging of messages that
+// each carry their own trace. The attributes of all traces share a
+// single allocation. Invalid traces get no attributes.
+func AttrsForTraces(traces []Trace, opts ...ExtractorOption) [][]slog.Attr {
+	cfg := newExtractorConfig(opts)
+	out := make([][]slog.Attr, len(traces))
+	buf := make([]slog.Attr, 0, 3*len(traces))
+	for i, trace := range traces {
+		if !trace.Valid() {
+			continue
+		}
+		if cfg.group != "" {
+			out[i] = cfg.traceAttrs(trace)
+			continue
+		}
+		start := len(buf)
+		buf = cfg.appendTraceAttrs(buf, trace)
+		out[i] = buf[start:len(buf):len(buf)]
+	}
+	return out
+}
+
+var defaultConfig = newExtractorConfig(nil)
+
+// groupConfig yields the attributes for [GroupValue].
+var groupConfig = newExtractorConfig([]ExtractorOption{WithGroup("trace")})
+
+// GroupValue returns the Trace stored in ctx as a group value with the
+// attributes id, span and sampled, to be added under any key by the
+// caller. It reports false if ctx holds no valid trace.
+func GroupValue(ctx context.Context) (slog.Value, bool) {
+	trace, ok := FromContext(ctx)
+	if !ok || !trace.Valid() {
+		return slog.Value{}, false
+	}
+	return groupConfig.traceAttrs(trace)[0].Value, true
+}
+
+// TraceParentExtractor is function suitable for use as an extractor
+// function for the [github.com/veqryn/slog-context] package to prepend
+// or append the trace information from the context. It returns the
+// attributes of [TraceAttrs] followed by those of [RequestAttrs].
+func TraceParentExtractor(ctx context.Context, recordT time.Time, recordLvl slog.Level, recordMsg string) []slog.Attr {
+	return append(TraceAttrs(ctx), RequestAttrs(ctx)...)
+}
+
+// TraceAttrs returns the attributes of the Trace stored in ctx with the
+// default keys of [TraceParentExtractor], or nil if ctx holds no valid
+// trace. It is the building block for code that combines the trace
+// with attributes of its own, like an access log line.
+func TraceAttrs(ctx context.Context) []slog.Attr {
+	trace, ok := FromContext(ctx)
+	if !ok || !trace.Valid() {
+		return nil
+	}
+	return defaultConfig.traceAttrs(trace)
+}
+
+// RequestAttrs returns the attributes of the request metadata other
+// than the trace stored in ctx by the middleware, currently the request
+// id of [WithRequestIDHeader]. It returns nil if there is none.
+func RequestAttrs(ctx context.Context) []slog.Attr {
+	if id, ok := RequestIDFromContext(ctx); ok {
+		return []slog.Attr{slog.String("requestID", id)}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package traceparent_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	traceparent "github.com/jum/slog-traceparent"
+)
+
+func TestExtractorKeys(t *testing.T) {
+	extract := traceparent.NewExtractor(
+		traceparent.WithTraceIDKey("trace_id"),
+		traceparent.WithSpanIDKey("span_id"),
+		traceparent.WithSampledKey("trace.sampled"),
+	)
+	ctx := traceparent.ContextWithTraceparent(context.Background(), validHeader)
+	attrs := extract(ctx, time.Now(), slog.LevelInfo, "msg")
+	want := []string{"trace_id", "span_id", "trace.sampled"}
+	if len(attrs) != len(want) {
+		t.Fatalf("attrs = %v, want the keys %v", attrs, want)
+	}
+	for i, key := range want {
+		if attrs[i].Key != key {
+			t.Errorf("attr %d key = %q, want %q", i, attrs[i].Key, key)
+		}
+	}
+	values := attrValues(attrs)
+	if values["trace_id"] != traceID || values["span_id"] != spanID || values["trace.sampled"] != "true" {
+		t.Errorf("attrs = %v", values)
+	}
+	defaults := attrValues(traceparent.TraceParentExtractor(ctx, time.Now(), slog.LevelInfo, "msg"))
+	if defaults["traceID"] != traceID || defaults["spanID"] != spanID || defaults["traceSampled"] != "true" {
+		t.Errorf("default attrs = %v", defaults)
+	}
+}
+
+func TestExtractorGroup(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	extract := traceparent.NewExtractor(traceparent.WithGroup("trace"))
+	ctx := traceparent.ContextWithTraceparent(context.Background(), validHeader)
+	attrs := extract(ctx, time.Now(), slog.LevelInfo, "msg")
+	if len(attrs) != 1 {
+		t.Fatalf("attrs = %v, want a single group", attrs)
+	}
+	logger.LogAttrs(context.Background(), slog.LevelInfo, "msg", attrs...)
+	var record struct {
+		Trace struct {
+			ID      string `json:"id"`
+			Span    string `json:"span"`
+			Sampled *bool  `json:"sampled"`
+		} `json:"trace"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Trace.ID != traceID || record.Trace.Span != spanID || record.Trace.Sampled == nil || !*record.Trace.Sampled {
+		t.Errorf("logged %s", buf.Bytes())
+	}
+}
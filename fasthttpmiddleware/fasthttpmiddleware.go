@@ -0,0 +1,63 @@
+// Package fasthttpmiddleware parses the traceparent header of fasthttp
+// requests. It lives in its own package to keep the fasthttp
+// dependency optional.
+//
+// As fasthttp does not pass a [context.Context] to handlers, the
+// [traceparent.Trace] is stored as a user value of the
+// [fasthttp.RequestCtx]. Use [Context] to obtain a context.Context
+// carrying the trace for the context-aware [log/slog] functions:
+//
+//	slog.InfoContext(fasthttpmiddleware.Context(ctx), "handled")
+package fasthttpmiddleware
+
+import (
+	"context"
+
+	traceparent "github.com/jum/slog-traceparent"
+	"github.com/valyala/fasthttp"
+)
+
+type userValueKeyT struct{}
+
+// headerCarrier adapts the request headers to [traceparent.Carrier].
+type headerCarrier struct {
+	h *fasthttp.RequestHeader
+}
+
+func (c headerCarrier) Get(key string) string {
+	return string(c.h.Peek(key))
+}
+
+func (c headerCarrier) Set(key, value string) {
+	c.h.Set(key, value)
+}
+
+// New creates a middleware that stores the trace parsed from the
+// traceparent and tracestate headers as a user value of the request
+// before calling next.
+func New(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		if trace, ok := traceparent.Extract(headerCarrier{&ctx.Request.Header}); ok {
+			ctx.SetUserValue(userValueKeyT{}, trace)
+		}
+		next(ctx)
+	}
+}
+
+// FromRequestCtx returns the Trace stored in ctx by [New] and whether
+// one was present.
+func FromRequestCtx(ctx *fasthttp.RequestCtx) (traceparent.Trace, bool) {
+	trace, ok := ctx.UserValue(userValueKeyT{}).(traceparent.Trace)
+	return trace, ok
+}
+
+// Context returns a [context.Context] derived from ctx that carries
+// the Trace stored by [New], suitable for [traceparent.FromContext]
+// and the context-aware logging functions.
+func Context(ctx *fasthttp.RequestCtx) context.Context {
+	trace, ok := FromRequestCtx(ctx)
+	if !ok {
+		return ctx
+	}
+	return trace.Context(ctx)
+}
@@ -0,0 +1,69 @@
+package fasthttpmiddleware_test
+
+import (
+	"net"
+	"testing"
+
+	traceparent "github.com/jum/slog-traceparent"
+	"github.com/jum/slog-traceparent/fasthttpmiddleware"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+const header = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestInmemoryServer(t *testing.T) {
+	ln := fasthttputil.NewInmemoryListener()
+	defer ln.Close()
+	type result struct {
+		trace, fromContext traceparent.Trace
+		ok, okContext      bool
+	}
+	results := make(chan result, 1)
+	srv := &fasthttp.Server{Handler: fasthttpmiddleware.New(func(ctx *fasthttp.RequestCtx) {
+		var res result
+		res.trace, res.ok = fasthttpmiddleware.FromRequestCtx(ctx)
+		res.fromContext, res.okContext = traceparent.FromContext(fasthttpmiddleware.Context(ctx))
+		results <- res
+	})}
+	go srv.Serve(ln)
+	defer srv.Shutdown()
+	client := &fasthttp.Client{Dial: func(string) (net.Conn, error) { return ln.Dial() }}
+
+	for _, tt := range []struct {
+		name, header string
+		ok           bool
+	}{
+		{"valid", header, true},
+		{"malformed", "00-xyz-1-01", false},
+		{"missing", "", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := fasthttp.AcquireRequest()
+			defer fasthttp.ReleaseRequest(req)
+			resp := fasthttp.AcquireResponse()
+			defer fasthttp.ReleaseResponse(resp)
+			req.SetRequestURI("http://example.com/")
+			if tt.header != "" {
+				req.Header.Set("traceparent", tt.header)
+				req.Header.Set("tracestate", "vendor=value")
+			}
+			if err := client.Do(req, resp); err != nil {
+				t.Fatal(err)
+			}
+			res := <-results
+			if res.ok != tt.ok || res.okContext != tt.ok {
+				t.Fatalf("found %v in RequestCtx and %v in Context, want %v", res.ok, res.okContext, tt.ok)
+			}
+			if !tt.ok {
+				return
+			}
+			if res.trace.Header() != header || res.trace.State != "vendor=value" {
+				t.Errorf("trace %v state %q", res.trace, res.trace.State)
+			}
+			if !res.fromContext.Equal(res.trace) {
+				t.Errorf("Context holds %v, want %v", res.fromContext, res.trace)
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+module github.com/jum/slog-traceparent/fasthttpmiddleware
+
+go 1.24
+
+require (
+	github.com/jum/slog-traceparent v1.1.0
+	github.com/valyala/fasthttp v1.65.0
+)
+
+require (
+	github.com/andybalholm/brotli v1.2.0 // indirect
+	github.com/klauspost/compress v1.18.0 // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+)
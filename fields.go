@@ -0,0 +1,40 @@
+package traceparent
+
+import (
+	"context"
+	"strconv"
+)
+
+// TraceFields returns the Trace stored in ctx as ordered key/value
+// pairs using the default attribute keys of [TraceParentExtractor], for
+// use by logging libraries other than [log/slog]. It reports false if
+// ctx holds no valid trace.
+func TraceFields(ctx context.Context) ([]string, bool) {
+	trace, ok := FromContext(ctx)
+	if !ok || !trace.Valid() {
+		return nil, false
+	}
+	fields := []string{"traceID", trace.ID.String()}
+	if !trace.SpanID.IsZero() {
+		fields = append(fields, "spanID", trace.SpanID.String())
+	}
+	return append(fields, "traceSampled", strconv.FormatBool(trace.Sampled)), true
+}
+
+// TraceIDFromContext returns the trace-id of the Trace stored in ctx as
+// lowercase hex, or an empty string if ctx holds no valid trace. It
+// suits a template function showing the trace-id on error pages:
+//
+//	tmpl := template.New("error").Funcs(template.FuncMap{
+//		"traceID": traceparent.TraceIDFromContext,
+//	})
+//	// {{traceID .Ctx}} in the template
+//
+// The hex digits need no escaping by [html/template].
+func TraceIDFromContext(ctx context.Context) string {
+	trace, ok := FromContext(ctx)
+	if !ok || !trace.Valid() {
+		return ""
+	}
+	return trace.ID.String()
+}
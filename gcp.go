@@ -0,0 +1,35 @@
+package traceparent
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// NewGCPExtractor returns an extractor function for the
+// [github.com/veqryn/slog-context] package that formats the trace
+// information for log correlation in Google Cloud Logging, using the
+// special logging.googleapis.com/trace, logging.googleapis.com/spanId
+// and logging.googleapis.com/trace_sampled keys. Of opts only
+// [WithExtractorContextKey] applies.
+func NewGCPExtractor(projectID string, opts ...ExtractorOption) func(ctx context.Context, recordT time.Time, recordLvl slog.Level, recordMsg string) []slog.Attr {
+	cfg := &extractorConfig{
+		contextKey: traceContextKeyT{},
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	prefix := "projects/" + projectID + "/traces/"
+	return func(ctx context.Context, recordT time.Time, recordLvl slog.Level, recordMsg string) []slog.Attr {
+		trace, ok := FromContextWithKey(ctx, cfg.contextKey)
+		if !ok || !trace.Valid() {
+			return nil
+		}
+		attrs := make([]slog.Attr, 0, 3)
+		attrs = append(attrs, slog.String("logging.googleapis.com/trace", prefix+trace.ID.String()))
+		if !trace.SpanID.IsZero() {
+			attrs = append(attrs, slog.String("logging.googleapis.com/spanId", trace.SpanID.String()))
+		}
+		return append(attrs, slog.Bool("logging.googleapis.com/trace_sampled", trace.Sampled))
+	}
+}
@@ -0,0 +1,39 @@
+package traceparent_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	traceparent "github.com/jum/slog-traceparent"
+)
+
+func TestGCPExtractor(t *testing.T) {
+	extract := traceparent.NewGCPExtractor("my-project")
+	ctx := traceparent.ContextWithTraceparent(context.Background(), validHeader)
+	attrs := attrValues(extract(ctx, time.Now(), slog.LevelInfo, "msg"))
+	want := map[string]string{
+		"logging.googleapis.com/trace":         "projects/my-project/traces/" + traceID,
+		"logging.googleapis.com/spanId":        spanID,
+		"logging.googleapis.com/trace_sampled": "true",
+	}
+	if len(attrs) != len(want) {
+		t.Errorf("attrs = %v, want %v", attrs, want)
+	}
+	for key, value := range want {
+		if attrs[key] != value {
+			t.Errorf("%s = %q, want %q", key, attrs[key], value)
+		}
+	}
+	if attrs := extract(context.Background(), time.Now(), slog.LevelInfo, "msg"); attrs != nil {
+		t.Errorf("attrs = %v without a trace", attrs)
+	}
+
+	key := traceparent.NewContextKey("gcp")
+	trace, _ := traceparent.FromContext(ctx)
+	keyed := traceparent.NewGCPExtractor("my-project", traceparent.WithExtractorContextKey(key))
+	if attrs := attrValues(keyed(trace.ContextWithKey(context.Background(), key), time.Now(), slog.LevelInfo, "msg")); attrs["logging.googleapis.com/spanId"] != spanID {
+		t.Errorf("attrs = %v, want the trace under the custom key", attrs)
+	}
+}
@@ -0,0 +1,161 @@
+package traceparent
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"log/slog"
+	mathrand "math/rand/v2"
+	"time"
+)
+
+// IDGenerator creates the ids for synthesized traces and spans. The
+// ids returned must not be all zero.
+type IDGenerator interface {
+	NewTraceID() [16]byte
+	NewSpanID() [8]byte
+}
+
+// RandomIDGenerator is the default [IDGenerator], creating random
+// ids using [crypto/rand].
+type RandomIDGenerator struct{}
+
+// NewTraceID implements [IDGenerator].
+func (RandomIDGenerator) NewTraceID() [16]byte {
+	return NewTraceID()
+}
+
+// NewSpanID implements [IDGenerator].
+func (RandomIDGenerator) NewSpanID() [8]byte {
+	return NewSpanID()
+}
+
+// derivedIDGenerator derives span-ids from a hash of key, see
+// [WithDerivedSpanID]. Trace-ids are taken from base.
+type derivedIDGenerator struct {
+	base IDGenerator
+	key  string
+}
+
+func (gen derivedIDGenerator) NewTraceID() [16]byte {
+	return gen.base.NewTraceID()
+}
+
+func (gen derivedIDGenerator) NewSpanID() [8]byte {
+	sum := sha256.Sum256([]byte(gen.key))
+	id := SpanID(sum[:8])
+	if id.IsZero() {
+		return gen.base.NewSpanID()
+	}
+	return id
+}
+
+// timeoutIDGenerator falls back to math/rand if base does not return
+// an id within timeout or before ctx is done, see [WithIDTimeout].
+type timeoutIDGenerator struct {
+	ctx     context.Context
+	base    IDGenerator
+	timeout time.Duration
+	logger  *slog.Logger
+}
+
+func (gen timeoutIDGenerator) NewTraceID() [16]byte {
+	return awaitID(gen, gen.base.NewTraceID, func() [16]byte {
+		var id [16]byte
+		for TraceID(id).IsZero() {
+			binary.BigEndian.PutUint64(id[:8], mathrand.Uint64())
+			binary.BigEndian.PutUint64(id[8:], mathrand.Uint64())
+		}
+		return id
+	})
+}
+
+func (gen timeoutIDGenerator) NewSpanID() [8]byte {
+	return awaitID(gen, gen.base.NewSpanID, func() [8]byte {
+		var id [8]byte
+		for SpanID(id).IsZero() {
+			binary.BigEndian.PutUint64(id[:], mathrand.Uint64())
+		}
+		return id
+	})
+}
+
+// awaitID returns the id created by newID, or by fallback if that
+// takes too long. A blocked newID is left to finish in the background.
+func awaitID[T any](gen timeoutIDGenerator, newID, fallback func() T) T {
+	ch := make(chan T, 1)
+	go func() {
+		ch <- newID()
+	}()
+	timer := time.NewTimer(gen.timeout)
+	defer timer.Stop()
+	select {
+	case id := <-ch:
+		return id
+	case <-timer.C:
+	case <-gen.ctx.Done():
+	}
+	gen.logger.WarnContext(gen.ctx, "traceparent: id generation timed out, falling back to math/rand")
+	return fallback()
+}
+
+// NewTraceID returns a random trace-id.
+func NewTraceID() TraceID {
+	var id TraceID
+	for id.IsZero() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+// NewSpanID returns a random span-id.
+func NewSpanID() SpanID {
+	var id SpanID
+	for id.IsZero() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+// NewTrace returns a new, unsampled Trace with a random trace-id and
+// span-id. The random trace-id flag is set.
+func NewTrace() Trace {
+	return Trace{
+		ID:     NewTraceID(),
+		SpanID: NewSpanID(),
+		Flags:  FlagRandom,
+		Random: true,
+	}
+}
+
+// Child returns a copy of the Trace for a child span, with a new
+// span-id from gen and ParentSpanID set to the current span-id. The
+// trace-id and flags are unchanged, Raw is cleared as it no longer
+// matches. If gen is nil [RandomIDGenerator] is used.
+func (trace Trace) Child(gen IDGenerator) Trace {
+	if gen == nil {
+		gen = RandomIDGenerator{}
+	}
+	trace.ParentSpanID = trace.SpanID
+	trace.SpanID = gen.NewSpanID()
+	trace.SpanSynthesized = false
+	trace.Raw = ""
+	return trace
+}
+
+// WithSynthesizedSpan returns a copy of the Trace with a span-id from
+// gen if it has none, marked by SpanSynthesized, so it can be sent on
+// in a valid traceparent header. If gen is nil [RandomIDGenerator] is
+// used.
+func (trace Trace) WithSynthesizedSpan(gen IDGenerator) Trace {
+	if !trace.SpanID.IsZero() {
+		return trace
+	}
+	if gen == nil {
+		gen = RandomIDGenerator{}
+	}
+	trace.SpanID = gen.NewSpanID()
+	trace.SpanSynthesized = true
+	return trace
+}
@@ -0,0 +1,165 @@
+package traceparent_test
+
+import (
+	"bytes"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	traceparent "github.com/jum/slog-traceparent"
+)
+
+var headerPattern = regexp.MustCompile(`^00-` + traceID + `-([0-9a-f]{16})-01$`)
+
+func TestHeaderSynthesizesSpan(t *testing.T) {
+	id, _ := traceparent.ParseTraceID(traceID)
+	trace := traceparent.Trace{ID: id, Sampled: true}
+	header := trace.Header()
+	m := headerPattern.FindStringSubmatch(header)
+	if m == nil || m[1] == "0000000000000000" {
+		t.Fatalf("Header() = %q, want a valid header with a synthesized span-id", header)
+	}
+	if _, err := traceparent.ParseTraceparent(header); err != nil {
+		t.Errorf("ParseTraceparent(%q) = %v", header, err)
+	}
+
+	synthesized := trace.WithSynthesizedSpan(nil)
+	if !synthesized.SpanSynthesized || synthesized.SpanID.IsZero() {
+		t.Fatalf("WithSynthesizedSpan = %v, want a marked span-id", synthesized)
+	}
+	if synthesized.Header() != synthesized.Header() {
+		t.Error("Header of a synthesized span is not stable")
+	}
+	if again := synthesized.WithSynthesizedSpan(nil); again.SpanID != synthesized.SpanID {
+		t.Error("WithSynthesizedSpan replaced an existing span-id")
+	}
+}
+
+func TestPropagationSynthesizesSpan(t *testing.T) {
+	id, _ := traceparent.ParseTraceID(traceID)
+	trace := traceparent.Trace{ID: id, Sampled: true}
+
+	carrier := traceparent.MapCarrier{}
+	traceparent.Inject(trace, carrier)
+	if !headerPattern.MatchString(carrier["traceparent"]) {
+		t.Errorf("Inject set %q", carrier["traceparent"])
+	}
+	if _, value := traceparent.InjectBytes(trace); !headerPattern.Match(value) {
+		t.Errorf("InjectBytes = %q", value)
+	}
+	w, _, _ := serve(t, []traceparent.Option{traceparent.WithXRayFallback(), traceparent.WithTraceResponse()},
+		http.Header{"X-Amzn-Trace-Id": {"Root=1-4bf92f35-77b34da6a3ce929d0e0e4736;Sampled=1"}})
+	if got := w.Header().Get("traceresponse"); !headerPattern.MatchString(got) {
+		t.Errorf("traceresponse = %q", got)
+	}
+	if key, value := traceparent.InjectBytes(traceparent.Trace{}); key != "traceparent" || value != nil {
+		t.Errorf("InjectBytes of the zero Trace = %q, %q", key, value)
+	}
+}
+
+func TestGenerateMissing(t *testing.T) {
+	_, got, _ := serve(t, []traceparent.Option{traceparent.WithGenerateMissing()}, nil)
+	if !got.Valid() || got.Sampled {
+		t.Fatalf("trace %v, want a valid unsampled trace", got)
+	}
+	if _, err := traceparent.ParseTraceparent(got.Header()); err != nil {
+		t.Errorf("generated ids do not validate: %v", err)
+	}
+	_, other, _ := serve(t, []traceparent.Option{traceparent.WithGenerateMissing()}, nil)
+	if other.ID == got.ID || other.SpanID == got.SpanID {
+		t.Errorf("two requests got the same ids %v", got)
+	}
+	_, kept, _ := serve(t, []traceparent.Option{traceparent.WithGenerateMissing()}, http.Header{"traceparent": {validHeader}})
+	if kept.ID.String() != traceID || kept.SpanID.String() != spanID {
+		t.Errorf("trace %v, want the inbound one", kept)
+	}
+	if _, none, _ := serve(t, nil, nil); none.Valid() {
+		t.Errorf("trace %v generated without the option", none)
+	}
+}
+
+func TestSampleRatio(t *testing.T) {
+	sampled := func(seed uint64) []bool {
+		opts := []traceparent.Option{
+			traceparent.WithGenerateMissing(),
+			traceparent.WithSampleRatio(0.25),
+			traceparent.WithSampleSource(rand.NewPCG(seed, seed)),
+		}
+		decisions := make([]bool, 1000)
+		for i := range decisions {
+			_, got, _ := serve(t, opts, nil)
+			decisions[i] = got.Sampled
+		}
+		return decisions
+	}
+	first, again := sampled(1), sampled(1)
+	n := 0
+	for i := range first {
+		if first[i] != again[i] {
+			t.Fatalf("decision %d differs for the same seed", i)
+		}
+		if first[i] {
+			n++
+		}
+	}
+	if n < 200 || n > 300 {
+		t.Errorf("%d of 1000 sampled, want about 250", n)
+	}
+
+	opts := []traceparent.Option{traceparent.WithGenerateMissing(), traceparent.WithSampleRatio(1)}
+	unsampled := "00-" + traceID + "-" + spanID + "-00"
+	if _, got, _ := serve(t, opts, http.Header{"traceparent": {unsampled}}); got.Sampled {
+		t.Error("sample ratio overrode the inbound decision")
+	}
+	if _, got, _ := serve(t, opts, nil); !got.Sampled {
+		t.Error("synthesized trace not sampled with ratio 1")
+	}
+}
+
+// blockingGenerator simulates a crypto source starved of entropy, its
+// calls block until release is closed.
+type blockingGenerator struct {
+	release chan struct{}
+}
+
+func (gen blockingGenerator) NewTraceID() [16]byte {
+	<-gen.release
+	return [16]byte{1}
+}
+
+func (gen blockingGenerator) NewSpanID() [8]byte {
+	<-gen.release
+	return [8]byte{1}
+}
+
+func TestIDTimeout(t *testing.T) {
+	gen := blockingGenerator{release: make(chan struct{})}
+	defer close(gen.release)
+	var buf bytes.Buffer
+	opts := []traceparent.Option{
+		traceparent.WithGenerateMissing(),
+		traceparent.WithChildSpan(),
+		traceparent.WithIDGenerator(gen),
+		traceparent.WithIDTimeout(10 * time.Millisecond),
+		traceparent.WithErrorLogger(slog.New(slog.NewTextHandler(&buf, nil))),
+	}
+	start := time.Now()
+	_, got, _ := serve(t, opts, nil)
+	if !got.Valid() || got.ID == [16]byte{1} {
+		t.Errorf("trace %v, want one with fallback ids", got)
+	}
+	_, child, _ := serve(t, opts, http.Header{"traceparent": {validHeader}})
+	if child.SpanID.IsZero() || child.SpanID == [8]byte{1} || child.ParentSpanID.String() != spanID {
+		t.Errorf("child %v, want a fallback span-id", child)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("requests took %v despite the timeout", d)
+	}
+	if n := strings.Count(buf.String(), "falling back to math/rand"); n < 3 {
+		t.Errorf("logged %d fallback warnings, want one per id:\n%s", n, buf.String())
+	}
+}
@@ -0,0 +1,110 @@
+// Package ginmiddleware adapts the [traceparent.New] middleware to the
+// Gin framework. It lives in its own package to keep the Gin
+// dependency optional.
+package ginmiddleware
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	traceparent "github.com/jum/slog-traceparent"
+)
+
+type ginContextKeyT struct{}
+
+// request carries the Gin context through the core middleware.
+type request struct {
+	c      *gin.Context
+	called bool
+}
+
+// Middleware returns a [gin.HandlerFunc] that injects the
+// [traceparent.Trace] into the context of c.Request, configured by the
+// same opts as [traceparent.New]. The remaining handlers write their
+// response through the core middleware, so options like
+// [traceparent.WithResponseLogging] see it. If the core middleware does
+// not pass the request on, the remaining handlers are aborted.
+func Middleware(opts ...traceparent.Option) gin.HandlerFunc {
+	h := traceparent.New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := r.Context().Value(ginContextKeyT{}).(*request)
+		req.called = true
+		req.c.Request = r
+		writer := req.c.Writer
+		req.c.Writer = &handlerWriter{ResponseWriter: writer, w: w}
+		defer func() {
+			req.c.Writer = writer
+		}()
+		req.c.Next()
+	}), opts...)
+	return func(c *gin.Context) {
+		req := &request{c: c}
+		h.ServeHTTP(coreWriter{c.Writer}, c.Request.WithContext(context.WithValue(c.Request.Context(), ginContextKeyT{}, req)))
+		if !req.called {
+			c.Abort()
+		}
+	}
+}
+
+// coreWriter is the Gin writer as passed to the core middleware. It
+// hides the Unwrap method of the Gin writer, which
+// [traceparent.WithRequireOutermost] takes for a preceding middleware,
+// and forwards the deadlines of [http.ResponseController] instead.
+type coreWriter struct {
+	gin.ResponseWriter
+}
+
+func (w coreWriter) SetReadDeadline(deadline time.Time) error {
+	return http.NewResponseController(w.ResponseWriter).SetReadDeadline(deadline)
+}
+
+func (w coreWriter) SetWriteDeadline(deadline time.Time) error {
+	return http.NewResponseController(w.ResponseWriter).SetWriteDeadline(deadline)
+}
+
+func (w coreWriter) EnableFullDuplex() error {
+	return http.NewResponseController(w.ResponseWriter).EnableFullDuplex()
+}
+
+// handlerWriter is the Gin writer of the handlers after the
+// middleware. The response is written to w, the ResponseWriter of the
+// core middleware, while the state like Status and Size is read from
+// the Gin writer below it.
+type handlerWriter struct {
+	gin.ResponseWriter
+	w http.ResponseWriter
+}
+
+func (w *handlerWriter) Header() http.Header {
+	return w.w.Header()
+}
+
+func (w *handlerWriter) WriteHeader(status int) {
+	w.w.WriteHeader(status)
+}
+
+func (w *handlerWriter) Write(b []byte) (int, error) {
+	return w.w.Write(b)
+}
+
+func (w *handlerWriter) WriteString(s string) (int, error) {
+	return io.WriteString(w.w, s)
+}
+
+func (w *handlerWriter) Flush() {
+	http.NewResponseController(w.w).Flush()
+}
+
+func (w *handlerWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.w).Hijack()
+}
+
+// Unwrap allows [http.ResponseController] to reach the ResponseWriter
+// of the core middleware.
+func (w *handlerWriter) Unwrap() http.ResponseWriter {
+	return w.w
+}
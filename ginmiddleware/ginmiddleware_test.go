@@ -0,0 +1,86 @@
+package ginmiddleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	traceparent "github.com/jum/slog-traceparent"
+	"github.com/jum/slog-traceparent/ginmiddleware"
+)
+
+const header = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestMiddleware(t *testing.T) {
+	var logs, warnings bytes.Buffer
+	logger := slog.New(&traceparent.Handler{Next: slog.NewJSONHandler(&logs, nil)})
+	router := gin.New()
+	router.Use(ginmiddleware.Middleware(
+		traceparent.WithResponseLogging(logger),
+		traceparent.WithTraceResponse(),
+		traceparent.WithRequireOutermost(),
+		traceparent.WithErrorLogger(slog.New(slog.NewTextHandler(&warnings, nil))),
+	))
+	var (
+		got   traceparent.Trace
+		attrs []slog.Attr
+	)
+	router.GET("/tea", func(c *gin.Context) {
+		ctx := c.Request.Context()
+		got, _ = traceparent.FromContext(ctx)
+		attrs = traceparent.TraceParentExtractor(ctx, time.Now(), slog.LevelInfo, "")
+		c.String(http.StatusTeapot, "short")
+	})
+	r := httptest.NewRequest(http.MethodGet, "/tea", nil)
+	r.Header.Set("traceparent", header)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusTeapot || w.Body.String() != "short" {
+		t.Errorf("response %d %q", w.Code, w.Body)
+	}
+	if got.Header() != header {
+		t.Errorf("handler trace %v, want %s", got, header)
+	}
+	if len(attrs) == 0 || attrs[0].Value.String() != got.ID.String() {
+		t.Errorf("extractor attrs %v", attrs)
+	}
+	if w.Header().Get("traceresponse") != header {
+		t.Errorf("traceresponse %q", w.Header().Get("traceresponse"))
+	}
+	var line map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &line); err != nil {
+		t.Fatalf("log %q: %v", logs.String(), err)
+	}
+	if line["status"] != float64(http.StatusTeapot) || line["path"] != "/tea" || line["traceID"] != got.ID.String() {
+		t.Errorf("log line %v", line)
+	}
+	if warnings.Len() != 0 {
+		t.Errorf("unexpected warning %q", warnings.String())
+	}
+}
+
+func TestMiddlewareAbort(t *testing.T) {
+	router := gin.New()
+	router.Use(ginmiddleware.Middleware(traceparent.WithGate(func(trace traceparent.Trace) (bool, int) {
+		return trace.Valid(), http.StatusForbidden
+	})))
+	called := false
+	router.GET("/", func(c *gin.Context) {
+		called = true
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if called || w.Code != http.StatusForbidden {
+		t.Errorf("status %d called %v, want 403 and aborted", w.Code, called)
+	}
+}
@@ -0,0 +1,19 @@
+package traceparent
+
+import "context"
+
+// Go runs fn in a new goroutine with a fresh background context that
+// carries the Trace stored in ctx, if any.
+//
+// The context passed to fn is detached from ctx: it is not canceled
+// when ctx is, has no deadline and carries no other values. This lets
+// work outlive the request that started it while still logging the
+// request's trace, but fn is responsible for bounding its own
+// lifetime.
+func Go(ctx context.Context, fn func(context.Context)) {
+	detached := context.Background()
+	if trace, ok := FromContext(ctx); ok {
+		detached = trace.Context(detached)
+	}
+	go fn(detached)
+}
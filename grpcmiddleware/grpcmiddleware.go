@@ -0,0 +1,56 @@
+// Package grpcmiddleware propagates the [traceparent.Trace] over gRPC
+// metadata. It lives in its own package to keep the gRPC dependency
+// optional for users of the HTTP middleware.
+package grpcmiddleware
+
+import (
+	"context"
+
+	traceparent "github.com/jum/slog-traceparent"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// FromMetadata parses the traceparent and tracestate keys of md into a
+// [traceparent.Trace] and reports whether a valid trace was found.
+func FromMetadata(md metadata.MD) (traceparent.Trace, bool) {
+	values := md.Get("traceparent")
+	if len(values) == 0 {
+		return traceparent.Trace{}, false
+	}
+	trace, err := traceparent.ParseTraceparent(values[0])
+	if err != nil {
+		return traceparent.Trace{}, false
+	}
+	if states := md.Get("tracestate"); len(states) > 0 {
+		trace = trace.WithState(states[0])
+	}
+	return trace, true
+}
+
+// ToMetadata writes trace into the traceparent and tracestate keys of
+// md.
+func ToMetadata(trace traceparent.Trace, md metadata.MD) {
+	header := trace.Header()
+	if header == "" {
+		return
+	}
+	md.Set("traceparent", header)
+	if trace.State != "" {
+		md.Set("tracestate", trace.State)
+	}
+}
+
+// UnaryServerInterceptor returns a [grpc.UnaryServerInterceptor] that
+// injects the trace found in the incoming metadata into the handler
+// context.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if trace, ok := FromMetadata(md); ok {
+				ctx = trace.Context(ctx)
+			}
+		}
+		return handler(ctx, req)
+	}
+}
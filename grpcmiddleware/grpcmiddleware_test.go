@@ -0,0 +1,81 @@
+package grpcmiddleware_test
+
+import (
+	"context"
+	"testing"
+
+	traceparent "github.com/jum/slog-traceparent"
+	"github.com/jum/slog-traceparent/grpcmiddleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const header = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// call runs the interceptor with md as the incoming metadata and
+// returns the Trace the handler saw.
+func call(t *testing.T, md metadata.MD) (traceparent.Trace, bool) {
+	t.Helper()
+	ctx := context.Background()
+	if md != nil {
+		ctx = metadata.NewIncomingContext(ctx, md)
+	}
+	var (
+		got traceparent.Trace
+		ok  bool
+	)
+	handler := func(ctx context.Context, req any) (any, error) {
+		got, ok = traceparent.FromContext(ctx)
+		return req, nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	resp, err := grpcmiddleware.UnaryServerInterceptor()(ctx, "req", info, handler)
+	if err != nil || resp != "req" {
+		t.Fatalf("interceptor = %v, %v", resp, err)
+	}
+	return got, ok
+}
+
+func TestInterceptorRoundTrip(t *testing.T) {
+	in, err := traceparent.ParseTraceparent(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in = in.WithState("vendor=value")
+	md := metadata.MD{}
+	grpcmiddleware.ToMetadata(in, md)
+	got, ok := call(t, md)
+	if !ok || !got.Equal(in) || got.State != in.State {
+		t.Errorf("handler got %v state %q, want %v", got, got.State, in)
+	}
+	if got.Header() != header {
+		t.Errorf("Header() = %q, want %q", got.Header(), header)
+	}
+}
+
+func TestInterceptorAbsent(t *testing.T) {
+	for name, md := range map[string]metadata.MD{
+		"no metadata":    nil,
+		"no traceparent": metadata.Pairs("other", "value"),
+		"malformed":      metadata.Pairs("traceparent", "00-xyz-1-01"),
+	} {
+		if got, ok := call(t, md); ok {
+			t.Errorf("%s: handler got %v", name, got)
+		}
+	}
+}
+
+func TestToMetadataSpanless(t *testing.T) {
+	id, _ := traceparent.ParseTraceID("4bf92f3577b34da6a3ce929d0e0e4736")
+	md := metadata.MD{}
+	grpcmiddleware.ToMetadata(traceparent.Trace{ID: id, Sampled: true}, md)
+	trace, ok := grpcmiddleware.FromMetadata(md)
+	if !ok || trace.ID != id || trace.SpanID.IsZero() {
+		t.Errorf("metadata %v parsed to %v", md, trace)
+	}
+	md = metadata.MD{}
+	grpcmiddleware.ToMetadata(traceparent.Trace{}, md)
+	if len(md) != 0 {
+		t.Errorf("zero Trace set %v", md)
+	}
+}
@@ -0,0 +1,40 @@
+package traceparent
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Handler is a [slog.Handler] that adds the trace attributes of the
+// [Trace] stored in the context to each record before passing it on to
+// Next. This allows using the trace information without the
+// [github.com/veqryn/slog-context] package. The attributes are added to the
+// record, so a ReplaceAttr function of Next sees and may rename them
+// like any other attribute.
+type Handler struct {
+	Next slog.Handler
+}
+
+// Enabled implements [slog.Handler].
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.Next.Enabled(ctx, level)
+}
+
+// Handle implements [slog.Handler].
+func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+	if attrs := TraceParentExtractor(ctx, record.Time, record.Level, record.Message); len(attrs) > 0 {
+		record = record.Clone()
+		record.AddAttrs(attrs...)
+	}
+	return h.Next.Handle(ctx, record)
+}
+
+// WithAttrs implements [slog.Handler].
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Handler{Next: h.Next.WithAttrs(attrs)}
+}
+
+// WithGroup implements [slog.Handler].
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{Next: h.Next.WithGroup(name)}
+}
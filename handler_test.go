@@ -0,0 +1,51 @@
+package traceparent_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	traceparent "github.com/jum/slog-traceparent"
+)
+
+func TestHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(&traceparent.Handler{Next: slog.NewJSONHandler(&buf, nil)})
+	ctx := traceparent.ContextWithTraceparent(context.Background(), validHeader)
+
+	decode := func() map[string]any {
+		t.Helper()
+		var record map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+		buf.Reset()
+		return record
+	}
+
+	logger.InfoContext(ctx, "msg")
+	if record := decode(); record["traceID"] != traceID || record["spanID"] != spanID || record["traceSampled"] != true {
+		t.Errorf("record %v, want the trace attrs", record)
+	}
+
+	logger.InfoContext(context.Background(), "msg")
+	if record := decode(); record["traceID"] != nil {
+		t.Errorf("record %v, want no trace attrs", record)
+	}
+
+	logger.With("service", "api").WithGroup("request").InfoContext(ctx, "msg", "path", "/")
+	record := decode()
+	if record["service"] != "api" {
+		t.Errorf("record %v, want the service attr", record)
+	}
+	group, _ := record["request"].(map[string]any)
+	if group["path"] != "/" || group["traceID"] != traceID {
+		t.Errorf("record %v, want the attrs in the request group", record)
+	}
+
+	if !logger.Enabled(ctx, slog.LevelInfo) || logger.Enabled(ctx, slog.LevelDebug) {
+		t.Error("Enabled does not follow Next")
+	}
+}
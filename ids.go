@@ -0,0 +1,108 @@
+package traceparent
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+)
+
+// TraceID is a W3C trace-id. The zero value is not a valid trace-id.
+type TraceID [16]byte
+
+// SpanID is a W3C span-id, also called parent-id in the traceparent
+// header. The zero value is not a valid span-id.
+type SpanID [8]byte
+
+// ParseTraceID parses a trace-id of 32 lowercase hex digits.
+func ParseTraceID(s string) (TraceID, error) {
+	var id TraceID
+	if len(s) != 2*len(id) || !decodeLowerHex(id[:], s) {
+		return TraceID{}, fmt.Errorf("%w: invalid trace-id", ErrMalformed)
+	}
+	if id.IsZero() {
+		return TraceID{}, fmt.Errorf("%w: trace-id", ErrZeroID)
+	}
+	return id, nil
+}
+
+// ParseSpanID parses a span-id of 16 lowercase hex digits.
+func ParseSpanID(s string) (SpanID, error) {
+	var id SpanID
+	if len(s) != 2*len(id) || !decodeLowerHex(id[:], s) {
+		return SpanID{}, fmt.Errorf("%w: invalid span-id", ErrMalformed)
+	}
+	if id.IsZero() {
+		return SpanID{}, fmt.Errorf("%w: span-id", ErrZeroID)
+	}
+	return id, nil
+}
+
+// ParseFlags parses the two lowercase hex digits of the trace-flags
+// field. All 256 values are valid, bits unknown to this package are
+// kept.
+func ParseFlags(s string) (byte, error) {
+	if len(s) != 2 || !isLowerHex(s) {
+		return 0, fmt.Errorf("%w: flags %q", ErrInvalidFlags, s)
+	}
+	flags, err := strconv.ParseUint(s, 16, 8)
+	if err != nil {
+		return 0, fmt.Errorf("%w: flags %q", ErrInvalidFlags, s)
+	}
+	return byte(flags), nil
+}
+
+// String returns the trace-id as lowercase hex.
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsZero reports whether the trace-id is all zero.
+func (id TraceID) IsZero() bool {
+	return id == TraceID{}
+}
+
+// String returns the span-id as lowercase hex.
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsZero reports whether the span-id is all zero.
+func (id SpanID) IsZero() bool {
+	return id == SpanID{}
+}
+
+// lowerHex maps lowercase hex digits to their value and any other
+// byte to 0xff, so validation needs no branches per character.
+var lowerHex = func() (table [256]byte) {
+	for i := range table {
+		table[i] = 0xff
+	}
+	for c := '0'; c <= '9'; c++ {
+		table[c] = byte(c - '0')
+	}
+	for c := 'a'; c <= 'f'; c++ {
+		table[c] = byte(c-'a') + 10
+	}
+	return table
+}()
+
+// decodeLowerHex decodes the lowercase hex digits of s into dst, which
+// must be half as long as s, and reports whether s was valid.
+func decodeLowerHex(dst []byte, s string) bool {
+	var invalid byte
+	for i := range dst {
+		hi, lo := lowerHex[s[2*i]], lowerHex[s[2*i+1]]
+		invalid |= hi | lo
+		dst[i] = hi<<4 | lo
+	}
+	return invalid&0xf0 == 0
+}
+
+// isLowerHex reports whether s consists only of lowercase hex digits.
+func isLowerHex(s string) bool {
+	var invalid byte
+	for i := 0; i < len(s); i++ {
+		invalid |= lowerHex[s[i]]
+	}
+	return invalid&0xf0 == 0
+}
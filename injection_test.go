@@ -0,0 +1,73 @@
+package traceparent_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	traceparent "github.com/jum/slog-traceparent"
+)
+
+func TestLogInjection(t *testing.T) {
+	headers := []http.Header{
+		{"traceparent": {validHeader + "\n"}},
+		{"traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736\r\n-00f067aa0ba902b7-01"}},
+		{"traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01\r\nlevel=ERROR msg=forged"}},
+		{
+			"traceparent":  {validHeader},
+			"tracestate":   {"congo=a\nlevel=ERROR,rojo=b%0Aforged,acme=c\r"},
+			"baggage":      {"user=alice%0D%0Alevel=ERROR,tenant=x\ny"},
+			"X-Request-Id": {"id\r\nlevel=ERROR msg=forged"},
+		},
+	}
+	for _, validation := range []traceparent.ValidationLevel{traceparent.ValidationLenient, traceparent.ValidationStrict} {
+		for _, header := range headers {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&buf, nil))
+			extract := traceparent.NewExtractor(
+				traceparent.WithTracestateMembers("congo", "rojo", "acme"),
+				traceparent.WithRawTraceparentKey("traceparent"),
+			)
+			baggage := traceparent.NewBaggageExtractor("user", "tenant")
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctx := r.Context()
+				var attrs []slog.Attr
+				attrs = append(attrs, extract(ctx, time.Now(), slog.LevelInfo, "")...)
+				attrs = append(attrs, baggage(ctx, time.Now(), slog.LevelInfo, "")...)
+				for _, attr := range attrs {
+					if value := attr.Value.String(); strings.ContainsFunc(value, unsafeRune) {
+						t.Errorf("validation %v: attr %s = %q", validation, attr.Key, value)
+					}
+				}
+				logger.LogAttrs(context.Background(), slog.LevelInfo, "request", attrs...)
+			})
+			handler := traceparent.New(next,
+				traceparent.WithValidation(validation),
+				traceparent.WithBaggage(),
+				traceparent.WithRequestIDHeader("X-Request-Id"),
+				traceparent.WithErrorLogger(logger),
+			)
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for key, values := range header {
+				r.Header[http.CanonicalHeaderKey(key)] = values
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			for _, line := range strings.SplitAfter(buf.String(), "\n") {
+				if line != "" && !strings.HasPrefix(line, "time=") {
+					t.Errorf("validation %v, headers %q: forged log line %q", validation, header, line)
+				}
+			}
+		}
+	}
+}
+
+// unsafeRune reports whether r may not be logged unescaped.
+func unsafeRune(r rune) bool {
+	return r < 0x20 || r > 0x7e
+}
@@ -0,0 +1,73 @@
+package traceparent
+
+import (
+	"encoding/hex"
+	"encoding/json"
+)
+
+// traceJSON is the JSON form of a Trace.
+type traceJSON struct {
+	ID           string `json:"traceID"`
+	SpanID       string `json:"spanID,omitempty"`
+	ParentSpanID string `json:"parentSpanID,omitempty"`
+	Flags        string `json:"flags"`
+	State        string `json:"tracestate,omitempty"`
+}
+
+// MarshalJSON implements [json.Marshaler]. The ids and the effective
+// flags, see [Trace.EffectiveFlags], are encoded as lowercase hex strings
+// like in the traceparent header. A Trace without a trace-id is encoded
+// as null.
+func (trace Trace) MarshalJSON() ([]byte, error) {
+	if !trace.Valid() {
+		return []byte("null"), nil
+	}
+	v := traceJSON{
+		ID:    trace.ID.String(),
+		Flags: hex.EncodeToString([]byte{trace.EffectiveFlags()}),
+		State: trace.State,
+	}
+	if !trace.SpanID.IsZero() {
+		v.SpanID = trace.SpanID.String()
+	}
+	if !trace.ParentSpanID.IsZero() {
+		v.ParentSpanID = trace.ParentSpanID.String()
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements [json.Unmarshaler]. The ids and flags are
+// validated using the same rules as for the traceparent header. null
+// decodes to the zero Trace.
+func (trace *Trace) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*trace = Trace{}
+		return nil
+	}
+	var v traceJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	var t Trace
+	var err error
+	if t.ID, err = ParseTraceID(v.ID); err != nil {
+		return err
+	}
+	if v.SpanID != "" {
+		if t.SpanID, err = ParseSpanID(v.SpanID); err != nil {
+			return err
+		}
+	}
+	if v.ParentSpanID != "" {
+		if t.ParentSpanID, err = ParseSpanID(v.ParentSpanID); err != nil {
+			return err
+		}
+	}
+	if t.Flags, err = ParseFlags(v.Flags); err != nil {
+		return err
+	}
+	t.Sampled = t.Flags&FlagSampled != 0
+	t.Random = t.Flags&FlagRandom != 0
+	*trace = t.withState(v.State)
+	return nil
+}
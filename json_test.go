@@ -0,0 +1,79 @@
+package traceparent_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	traceparent "github.com/jum/slog-traceparent"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	parsed, err := traceparent.ParseTraceparent("00-" + traceID + "-" + spanID + "-00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := traceparent.ParseTraceID(traceID)
+	span, _ := traceparent.ParseSpanID(spanID)
+	sampled := parsed
+	sampled.Sampled = true
+	tests := map[string]traceparent.Trace{
+		"parsed":              parsed.WithState("a=1,b=2"),
+		"sampled after parse": sampled,
+		"hand-built":          {ID: id, SpanID: span, Sampled: true, Random: true},
+		"child":               parsed.Child(nil),
+		"span-less":           {ID: id},
+	}
+	for name, in := range tests {
+		t.Run(name, func(t *testing.T) {
+			data, err := json.Marshal(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out traceparent.Trace
+			if err := json.Unmarshal(data, &out); err != nil {
+				t.Fatalf("Unmarshal(%s) = %v", data, err)
+			}
+			if !out.Equal(in) || out.ParentSpanID != in.ParentSpanID || out.State != in.State {
+				t.Errorf("%s round-tripped to %v, want %v", data, out, in)
+			}
+			if out.Sampled != in.Sampled || out.Random != in.Random {
+				t.Errorf("%s: sampled %v random %v, want %v %v", data, out.Sampled, out.Random, in.Sampled, in.Random)
+			}
+		})
+	}
+}
+
+func TestJSONZero(t *testing.T) {
+	data, err := json.Marshal(struct {
+		Trace traceparent.Trace `json:"trace"`
+	}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"trace":null}` {
+		t.Errorf("zero Trace marshaled to %s", data)
+	}
+	out := traceparent.Trace{Sampled: true}
+	if err := json.Unmarshal([]byte("null"), &out); err != nil || !out.IsZero() {
+		t.Errorf("null unmarshaled to %v, %v", out, err)
+	}
+}
+
+func TestJSONInvalid(t *testing.T) {
+	for _, data := range []string{
+		`{"traceID":"00000000000000000000000000000000","flags":"01"}`,
+		`{"traceID":"4BF92F3577B34DA6A3CE929D0E0E4736","flags":"01"}`,
+		`{"traceID":"4bf92f3577b34da6a3ce929d0e0e473","flags":"01"}`,
+		`{"traceID":"` + traceID + `","spanID":"xyz","flags":"01"}`,
+		`{"traceID":"` + traceID + `","parentSpanID":"0000000000000000","flags":"01"}`,
+		`{"traceID":"` + traceID + `","flags":"1"}`,
+		`{"traceID":"` + traceID + `"}`,
+		`{}`,
+		`[]`,
+	} {
+		var trace traceparent.Trace
+		if err := json.Unmarshal([]byte(data), &trace); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded with %v", data, trace)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package traceparent
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// LoggerFor returns base with the attributes of the Trace stored in ctx
+// bound to it, for code that prefers passing a logger over passing a
+// context. If ctx holds no valid trace base is returned unchanged.
+// Loggers that have the extractor installed as well should be logged
+// to without a context, or the attributes appear twice.
+func LoggerFor(ctx context.Context, base *slog.Logger) *slog.Logger {
+	attrs := HandlerOptions(ctx)
+	if len(attrs) == 0 {
+		return base
+	}
+	args := make([]any, len(attrs))
+	for i, attr := range attrs {
+		args[i] = attr
+	}
+	return base.With(args...)
+}
+
+// HandlerOptions returns the attributes of the Trace stored in ctx, to
+// be set as the base attributes of a [slog.Handler] built for a single
+// request:
+//
+//	logger := slog.New(handler.WithAttrs(traceparent.HandlerOptions(ctx)))
+//
+// Like all attributes they are subject to the ReplaceAttr function of
+// the handler. It returns nil if ctx holds no valid trace.
+func HandlerOptions(ctx context.Context) []slog.Attr {
+	return TraceParentExtractor(ctx, time.Time{}, slog.LevelDebug, "")
+}
@@ -0,0 +1,10 @@
+module github.com/jum/slog-traceparent/logrushook
+
+go 1.24
+
+require (
+	github.com/jum/slog-traceparent v1.1.0
+	github.com/sirupsen/logrus v1.10.2
+)
+
+require golang.org/x/sys v0.35.0 // indirect
@@ -0,0 +1,33 @@
+// Package logrushook adds the trace information to logrus entries. It
+// lives in its own package to keep the logrus dependency optional.
+package logrushook
+
+import (
+	traceparent "github.com/jum/slog-traceparent"
+	"github.com/sirupsen/logrus"
+)
+
+// Hook is a [logrus.Hook] adding the fields of the
+// [traceparent.Trace] found in the entry context, set using
+// [logrus.WithContext], to each entry.
+type Hook struct{}
+
+// Levels implements [logrus.Hook].
+func (Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements [logrus.Hook].
+func (Hook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+	fields, ok := traceparent.TraceFields(entry.Context)
+	if !ok {
+		return nil
+	}
+	for i := 0; i+1 < len(fields); i += 2 {
+		entry.Data[fields[i]] = fields[i+1]
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package logrushook_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	traceparent "github.com/jum/slog-traceparent"
+	"github.com/jum/slog-traceparent/logrushook"
+	"github.com/sirupsen/logrus"
+)
+
+func TestHook(t *testing.T) {
+	trace, err := traceparent.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.AddHook(logrushook.Hook{})
+	logger.WithContext(trace.Context(context.Background())).Info("traced")
+	logger.WithContext(context.Background()).Info("untraced")
+	logger.Info("no context")
+
+	dec := json.NewDecoder(&buf)
+	var lines []map[string]any
+	for dec.More() {
+		var line map[string]any
+		if err := dec.Decode(&line); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3", len(lines))
+	}
+	want := map[string]any{
+		"traceID":      "4bf92f3577b34da6a3ce929d0e0e4736",
+		"spanID":       "00f067aa0ba902b7",
+		"traceSampled": "true",
+		"msg":          "traced",
+	}
+	for key, value := range want {
+		if lines[0][key] != value {
+			t.Errorf("%s = %v, want %v", key, lines[0][key], value)
+		}
+	}
+	for _, line := range lines[1:] {
+		if _, ok := line["traceID"]; ok {
+			t.Errorf("untraced line %v has a traceID", line)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package traceparent
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// New creates a middleware function that will inject the
+// [Trace] structure into the current requests context. To
+// make this context available to the [log/slog] logging functions, be
+// sure to use the variants including a [context] argument. Without
+// opts only a valid traceparent header results in a Trace being
+// injected, other requests are passed through unchanged. If a previous
+// middleware already injected a Trace matching the inbound header, that
+// Trace is kept, see [WithOverride].
+func New(next http.Handler, opts ...Option) http.Handler {
+	return newHandler(next, newConfig(opts))
+}
+
+// Setup returns a middleware constructor and a matching extractor for
+// the [github.com/veqryn/slog-context] package configured from the same
+// opts, so settings like [WithContextKey] cannot drift apart. Extractor
+// settings are given with [WithExtractorOptions].
+func Setup(opts ...Option) (func(http.Handler) http.Handler, func(ctx context.Context, recordT time.Time, recordLvl slog.Level, recordMsg string) []slog.Attr) {
+	cfg := newConfig(opts)
+	extractorOpts := append([]ExtractorOption{WithExtractorContextKey(cfg.contextKey)}, cfg.extractorOpts...)
+	middleware := func(next http.Handler) http.Handler {
+		return newHandler(next, cfg)
+	}
+	return middleware, NewExtractor(extractorOpts...)
+}
+
+func newHandler(next http.Handler, cfg *config) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if cfg.requireOutermost {
+			cfg.checkOutermost(w, r)
+		}
+		if cfg.skip != nil && cfg.skip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx := cfg.requestValues(r)
+		// A Trace stored by a preceding middleware for the same inbound
+		// trace is kept, only the parsing and storing is skipped.
+		trace, stored := Trace{}, false
+		if !cfg.override {
+			trace, stored = cfg.injected(r)
+		}
+		ok := stored
+		var invalid error
+		if !stored {
+			trace, ok, invalid = cfg.inbound(r)
+		}
+		if cfg.metrics != nil {
+			switch {
+			case ok:
+				cfg.metrics(ResultValid)
+			case invalid != nil:
+				cfg.metrics(ResultInvalid)
+			default:
+				cfg.metrics(ResultMissing)
+			}
+		}
+		if !ok && invalid != nil && cfg.invalidMetrics != nil {
+			cfg.invalidMetrics(ErrorField(invalid))
+		}
+		if !ok && invalid != nil && cfg.strict != nil {
+			cfg.strict.ServeHTTP(w, r)
+			return
+		}
+		if !ok && cfg.generateMissing {
+			trace, ok = cfg.generate(r), true
+		}
+		if ok && !stored && cfg.sampler != nil {
+			trace = trace.withSampled(cfg.sampler(r, trace))
+		}
+		if cfg.gate != nil {
+			if proceed, status := cfg.gate(trace); !proceed {
+				if status < 100 || status > 999 {
+					status = http.StatusNoContent
+				}
+				w.WriteHeader(status)
+				return
+			}
+		}
+		if ok {
+			if cfg.traceResponse {
+				if header := trace.Header(); header != "" {
+					w.Header().Set("traceresponse", header)
+				}
+			}
+			if cfg.serverTiming && trace.Sampled {
+				w.Header().Add("Server-Timing", `traceparent;desc="`+trace.ID.String()+`"`)
+			}
+			if !stored {
+				ctx = trace.ContextWithKey(ctx, cfg.contextKey)
+			}
+		} else if cfg.alwaysInject {
+			ctx = Trace{}.ContextWithKey(ctx, cfg.contextKey)
+		}
+		if ctx != r.Context() {
+			r = r.WithContext(ctx)
+		}
+		if cfg.latencyObserver != nil {
+			start := cfg.now()
+			defer func() {
+				cfg.latencyObserver(trace.Sampled, cfg.now().Sub(start))
+			}()
+		}
+		if cfg.responseLogger == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		sw := &statusWriter{ResponseWriter: w}
+		start := cfg.now()
+		next.ServeHTTP(sw, r)
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", sw.Status()),
+		}
+		if cfg.accessLog {
+			attrs = append(attrs, slog.Int64("bytes", sw.bytes), slog.Duration("duration", cfg.now().Sub(start)))
+		}
+		cfg.responseLogger.LogAttrs(ctx, slog.LevelInfo, "request", attrs...)
+	}
+	return http.HandlerFunc(fn)
+}
+
+// requestValues returns the context of r with the values other than
+// the trace stored, those already stored by a preceding middleware are
+// kept.
+func (cfg *config) requestValues(r *http.Request) context.Context {
+	ctx := r.Context()
+	if _, ok := StartTimeFromContext(ctx); cfg.startTime && !ok {
+		ctx = ContextWithStartTime(ctx, cfg.now())
+	}
+	if _, ok := BaggageFromContext(ctx); cfg.baggage && !ok {
+		if baggage := ParseBaggage(r.Header.Get("baggage")); baggage != nil {
+			ctx = ContextWithBaggage(ctx, baggage)
+		}
+	}
+	if _, ok := RequestIDFromContext(ctx); cfg.requestIDHeader != "" && !ok {
+		id := r.Header.Get(cfg.requestIDHeader)
+		if id == "" && cfg.generateMissing {
+			id = newRequestID()
+		}
+		if id != "" {
+			ctx = ContextWithRequestID(ctx, printable(id))
+		}
+	}
+	return ctx
+}
+
+// injected returns the valid Trace a previous middleware already
+// stored if it matches the inbound trace of r, as found in the headers
+// of the configured candidates. A request without any such header
+// matches as well.
+func (cfg *config) injected(r *http.Request) (Trace, bool) {
+	existing, ok := FromContextWithKey(r.Context(), cfg.contextKey)
+	if !ok || !existing.Valid() {
+		return Trace{}, false
+	}
+	carrier := HeaderCarrier(r.Header)
+	present := false
+	for _, cand := range cfg.candidates {
+		if cand.parse != nil {
+			if trace, err := cand.parse(carrier); err == nil {
+				return existing, matches(existing, trace)
+			}
+			continue
+		}
+		for _, header := range r.Header.Values(cand.name) {
+			if header == "" {
+				continue
+			}
+			if header == existing.Raw {
+				return existing, true
+			}
+			present = true
+			if trace, err := parseTraceparent(header, cfg.validation, cfg.lenientFlags); err == nil {
+				return existing, matches(existing, trace)
+			}
+		}
+	}
+	return existing, !present
+}
+
+// matches reports whether existing was stored for the inbound trace,
+// which it may have been replaced by a child span of. The sampled flag
+// is not compared as samplers may have overridden it.
+func matches(existing, trace Trace) bool {
+	trace.Sampled = existing.Sampled
+	parent := existing
+	parent.SpanID = existing.ParentSpanID
+	return trace.Equal(existing) || trace.Equal(parent)
+}
+
+// inbound returns the Trace sent with r and whether there is one. If r
+// carries a traceparent header without any valid value the parse error
+// of the first value is returned as well.
+func (cfg *config) inbound(r *http.Request) (Trace, bool, error) {
+	carrier := HeaderCarrier(r.Header)
+	var invalid error
+	for _, cand := range cfg.candidates {
+		if cand.parse != nil {
+			if trace, err := cand.parse(carrier); err == nil {
+				return cfg.inboundTrace(r, trace), true, invalid
+			}
+			continue
+		}
+		// Some proxies duplicate the header, the first valid value
+		// is used so a malformed one cannot shadow it.
+		headers := r.Header.Values(cand.name)
+		if len(headers) == 0 && cfg.queryParam != "" {
+			if header := r.URL.Query().Get(cfg.queryParam); header != "" {
+				headers = []string{header}
+			}
+		}
+		if len(headers) == 0 && cfg.cookieName != "" {
+			if cookie, err := r.Cookie(cfg.cookieName); err == nil && cookie.Value != "" {
+				headers = []string{cookie.Value}
+			}
+		}
+		if len(headers) == 0 && cfg.trailerFallback {
+			headers = r.Trailer.Values(cand.name)
+		}
+		var firstErr error
+		for _, header := range headers {
+			if header == "" {
+				continue
+			}
+			trace, err := parseTraceparent(header, cfg.validation, cfg.lenientFlags)
+			if err == nil {
+				trace.Raw = header
+				// The lenient rules accept headers that are not spec-valid
+				// and normalize them, those must not be forwarded verbatim.
+				if cfg.validation == ValidationLenient || cfg.lenientFlags {
+					if _, err := ParseTraceparent(header); err != nil {
+						trace.Raw = ""
+					}
+				}
+				return cfg.inboundTrace(r, trace), true, invalid
+			}
+			if firstErr == nil {
+				firstErr = err
+				if cfg.errorLogger != nil {
+					cfg.errorLogger.DebugContext(r.Context(), "traceparent: dropping invalid header",
+						slog.String("err", err.Error()), slog.String("field", ErrorField(err)),
+						slog.String("header", snippet(header)))
+				}
+			}
+		}
+		if invalid == nil {
+			invalid = firstErr
+		}
+	}
+	return Trace{}, false, invalid
+}
+
+// inboundTrace completes a Trace parsed from the headers of r.
+func (cfg *config) inboundTrace(r *http.Request, trace Trace) Trace {
+	if state := r.Header.Get("tracestate"); len(state) <= cfg.maxTracestateLen {
+		strict := cfg.validation == ValidationParanoid || cfg.validation == ValidationOTEL
+		trace.State, trace.StateMembers, trace.StateTruncated = parseTracestate(state, strict, cfg.maxStateMembers)
+	}
+	if cfg.tracestateSampling != "" {
+		// Flags keeps the bits as received, Raw no longer matches the
+		// decision if it changes.
+		if sampled, err := strconv.ParseBool(trace.StateMembers[cfg.tracestateSampling]); err == nil && sampled != trace.Sampled {
+			trace.Sampled = sampled
+			trace.Raw = ""
+		}
+	}
+	if cfg.synthesizeSpan {
+		trace = trace.WithSynthesizedSpan(cfg.generator(r))
+	}
+	if cfg.childSpan {
+		trace = trace.Child(cfg.generator(r))
+	}
+	return trace
+}
+
+// generate returns a new Trace for a request without one.
+func (cfg *config) generate(r *http.Request) Trace {
+	gen := cfg.generator(r)
+	trace := Trace{
+		ID:     gen.NewTraceID(),
+		SpanID: gen.NewSpanID(),
+	}
+	if _, ok := cfg.idGenerator.(RandomIDGenerator); ok {
+		trace.Flags = FlagRandom
+		trace.Random = true
+	}
+	if cfg.sample() {
+		trace.Flags |= FlagSampled
+		trace.Sampled = true
+	}
+	return trace
+}
+
+// generator returns the IDGenerator for new spans of r.
+func (cfg *config) generator(r *http.Request) IDGenerator {
+	gen := cfg.idGenerator
+	if cfg.idTimeout > 0 {
+		logger := cfg.errorLogger
+		if logger == nil {
+			logger = slog.Default()
+		}
+		gen = timeoutIDGenerator{ctx: r.Context(), base: gen, timeout: cfg.idTimeout, logger: logger}
+	}
+	if cfg.derivedSpanKey != nil {
+		if key := cfg.derivedSpanKey(r); key != "" {
+			return derivedIDGenerator{base: gen, key: key}
+		}
+	}
+	return gen
+}
+
+// snippet returns a shortened copy of header safe for logging, with
+// anything but printable ASCII replaced.
+func snippet(header string) string {
+	const max = 16
+	if len(header) > max {
+		return printable(header[:max]) + "..."
+	}
+	return printable(header)
+}
+
+// printable returns s with anything but printable ASCII replaced by
+// '?', so values received from clients cannot forge log lines.
+func printable(s string) string {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] > 0x7e {
+			b := []byte(s)
+			for j := i; j < len(b); j++ {
+				if b[j] < 0x20 || b[j] > 0x7e {
+					b[j] = '?'
+				}
+			}
+			return string(b)
+		}
+	}
+	return s
+}
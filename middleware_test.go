@@ -0,0 +1,132 @@
+package traceparent_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	traceparent "github.com/jum/slog-traceparent"
+)
+
+func TestChainedMiddlewares(t *testing.T) {
+	var (
+		got      traceparent.Trace
+		observed bool
+		results  []string
+	)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = traceparent.MustRequest(r)
+	})
+	inner := traceparent.New(next,
+		traceparent.WithTraceResponse(),
+		traceparent.WithServerTiming(),
+		traceparent.WithMetrics(func(result string) { results = append(results, result) }),
+		traceparent.WithLatencyObserver(func(bool, time.Duration) { observed = true }),
+	)
+	var outer traceparent.Trace
+	handler := traceparent.New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outer = traceparent.MustRequest(r)
+		inner.ServeHTTP(w, r)
+	}), traceparent.WithChildSpan())
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("traceparent", validHeader)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if got.ID != outer.ID || got.SpanID != outer.SpanID || got.ParentSpanID.String() != spanID {
+		t.Errorf("inner trace %v, want the child span %v of the outer middleware", got, outer)
+	}
+	if want := outer.Header(); w.Header().Get("traceresponse") != want {
+		t.Errorf("traceresponse = %q, want %q", w.Header().Get("traceresponse"), want)
+	}
+	if w.Header().Get("Server-Timing") == "" {
+		t.Error("Server-Timing not set for the stored trace")
+	}
+	if !observed {
+		t.Error("latency observer not called")
+	}
+	if len(results) != 1 || results[0] != traceparent.ResultValid {
+		t.Errorf("metrics = %v, want [%s]", results, traceparent.ResultValid)
+	}
+}
+
+func TestChainedMiddlewaresMismatch(t *testing.T) {
+	var got traceparent.Trace
+	inner := traceparent.New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = traceparent.MustRequest(r)
+	}))
+	other, err := traceparent.ParseTraceparent("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("traceparent", validHeader)
+	r = r.WithContext(other.Context(r.Context()))
+	inner.ServeHTTP(httptest.NewRecorder(), r)
+	if got.ID.String() != traceID {
+		t.Errorf("trace %v, want the inbound trace %s", got, traceID)
+	}
+}
+
+func TestChainedMiddlewaresLenient(t *testing.T) {
+	var got traceparent.Trace
+	opts := []traceparent.Option{traceparent.WithValidation(traceparent.ValidationLenient), traceparent.WithChildSpan()}
+	handler := traceparent.New(traceparent.New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = traceparent.MustRequest(r)
+	}), opts...), opts...)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("traceparent", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00F067AA0BA902B7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if got.ParentSpanID.String() != spanID {
+		t.Errorf("parent span %s, want %s: the inner middleware created a second child", got.ParentSpanID, spanID)
+	}
+}
+
+func TestGateStatusOutOfRange(t *testing.T) {
+	for _, status := range []int{-1, 0, 99, 1000, 1 << 20} {
+		opts := []traceparent.Option{traceparent.WithGate(func(traceparent.Trace) (bool, int) {
+			return false, status
+		})}
+		w, _, called := serve(t, opts, nil)
+		if called || w.Code != http.StatusNoContent {
+			t.Errorf("status %d: got %d called %v, want 204", status, w.Code, called)
+		}
+	}
+}
+
+func TestChildSpan(t *testing.T) {
+	var got traceparent.Trace
+	var sent string
+	client := &traceparent.Transport{Base: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		sent = r.Header.Get("traceparent")
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})}
+	handler := traceparent.New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = traceparent.MustRequest(r)
+		req := httptest.NewRequestWithContext(r.Context(), http.MethodGet, "http://example.com/", nil)
+		if _, err := client.RoundTrip(req); err != nil {
+			t.Fatal(err)
+		}
+	}), traceparent.WithChildSpan())
+	spans := map[traceparent.SpanID]bool{}
+	for range 3 {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("traceparent", validHeader)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+		if got.ID.String() != traceID || got.ParentSpanID.String() != spanID || !got.Sampled {
+			t.Errorf("trace %v, want a child of %s", got, validHeader)
+		}
+		if got.SpanID.IsZero() || got.SpanID.String() == spanID || spans[got.SpanID] {
+			t.Errorf("span-id %s, want a new one", got.SpanID)
+		}
+		spans[got.SpanID] = true
+		if want := "00-" + traceID + "-" + got.SpanID.String() + "-01"; sent != want {
+			t.Errorf("sent %q, want the child span %q", sent, want)
+		}
+	}
+	if _, got, _ := serve(t, nil, http.Header{"traceparent": {validHeader}}); !got.ParentSpanID.IsZero() {
+		t.Errorf("parent span-id %s set without the option", got.ParentSpanID)
+	}
+}
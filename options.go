@@ -0,0 +1,529 @@
+package traceparent
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Option configures the middleware returned by [New].
+type Option func(*config)
+
+type config struct {
+	contextKey         any
+	generateMissing    bool
+	b3Fallback         bool
+	xrayFallback       bool
+	maxTracestateLen   int
+	traceResponse      bool
+	baggage            bool
+	childSpan          bool
+	queryParam         string
+	sampleRatio        float64
+	sampleMu           sync.Mutex
+	sampleRand         *rand.Rand
+	skip               func(*http.Request) bool
+	errorLogger        *slog.Logger
+	startTime          bool
+	now                func() time.Time
+	alwaysInject       bool
+	headerName         string
+	datadogFallback    bool
+	strict             http.Handler
+	metrics            func(result string)
+	idGenerator        IDGenerator
+	override           bool
+	responseLogger     *slog.Logger
+	candidateNames     []string
+	candidates         []candidate
+	extractorOpts      []ExtractorOption
+	sampler            func(*http.Request, Trace) bool
+	tracestateSampling string
+	validation         ValidationLevel
+	latencyObserver    func(sampled bool, d time.Duration)
+	gate               func(Trace) (proceed bool, status int)
+	trailerFallback    bool
+	derivedSpanKey     func(*http.Request) string
+	accessLog          bool
+	requestIDHeader    string
+	requireOutermost   bool
+	outermostOnce      sync.Once
+	invalidMetrics     func(field string)
+	serverTiming       bool
+	lenientFlags       bool
+	cookieName         string
+	idTimeout          time.Duration
+	maxStateMembers    int
+	synthesizeSpan     bool
+}
+
+func newConfig(opts []Option) *config {
+	cfg := &config{
+		contextKey:       traceContextKeyT{},
+		maxTracestateLen: DefaultMaxTracestateLen,
+		now:              time.Now,
+		headerName:       "traceparent",
+		idGenerator:      RandomIDGenerator{},
+		validation:       ValidationStrict,
+		maxStateMembers:  MaxTracestateMembers,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	cfg.candidates = cfg.resolveCandidates()
+	return cfg
+}
+
+// WithGenerateMissing makes the middleware synthesize a new [Trace]
+// with random ids if the request carries no valid traceparent header.
+// The trace is unsampled unless [WithSampleRatio] is given. This is
+// useful for edge services that are the first hop.
+func WithGenerateMissing() Option {
+	return func(cfg *config) {
+		cfg.generateMissing = true
+	}
+}
+
+// WithHeaderCandidates makes the middleware try to parse a trace from
+// each of the header names in order, using the first that parses
+// successfully. The names b3 and X-B3-TraceId select the B3 parser,
+// X-Amzn-Trace-Id the X-Ray parser and X-Datadog-Trace-Id the Datadog
+// parser, any other name is parsed as a W3C traceparent header. This
+// replaces [WithHeaderName] and the fallback options.
+func WithHeaderCandidates(names ...string) Option {
+	return func(cfg *config) {
+		cfg.candidateNames = names
+	}
+}
+
+// WithB3Fallback makes the middleware parse the Zipkin B3 headers,
+// either the single b3 header or the X-B3-TraceId, X-B3-SpanId and
+// X-B3-Sampled headers, if the request carries no valid traceparent
+// header.
+func WithB3Fallback() Option {
+	return func(cfg *config) {
+		cfg.b3Fallback = true
+	}
+}
+
+// WithContextKey makes the middleware store the [Trace] under key
+// instead of the default key used by [FromContext]. Use
+// [NewContextKey] to create a key and [FromContextWithKey] to read the
+// trace back.
+func WithContextKey(key any) Option {
+	return func(cfg *config) {
+		cfg.contextKey = key
+	}
+}
+
+// WithXRayFallback makes the middleware parse the AWS X-Ray
+// X-Amzn-Trace-Id header if the request carries no valid traceparent
+// header, or B3 headers if [WithB3Fallback] is also given.
+func WithXRayFallback() Option {
+	return func(cfg *config) {
+		cfg.xrayFallback = true
+	}
+}
+
+// WithDatadogFallback makes the middleware parse the Datadog
+// x-datadog-* headers if the request carries no valid traceparent
+// header, nor any other fallback header enabled by an option.
+func WithDatadogFallback() Option {
+	return func(cfg *config) {
+		cfg.datadogFallback = true
+	}
+}
+
+// WithStrict makes the middleware call onError instead of the next
+// handler if the request carries a traceparent header that is invalid,
+// for example to respond with 400 Bad Request. Requests without a
+// traceparent header are passed on as usual, as are requests whose
+// valid trace was found by a fallback like [WithB3Fallback].
+func WithStrict(onError http.Handler) Option {
+	return func(cfg *config) {
+		cfg.strict = onError
+	}
+}
+
+// Results passed to the callback of [WithMetrics].
+const (
+	ResultValid   = "valid"
+	ResultInvalid = "invalid"
+	ResultMissing = "missing"
+)
+
+// WithMetrics makes the middleware call onResult once per request with
+// [ResultValid] if an inbound trace was found, [ResultInvalid] if a
+// traceparent header was present but invalid and [ResultMissing]
+// otherwise. Traces synthesized by [WithGenerateMissing] count as
+// missing, traces kept from a preceding middleware as valid. This allows counting the results with any metrics library.
+func WithMetrics(onResult func(result string)) Option {
+	return func(cfg *config) {
+		cfg.metrics = onResult
+	}
+}
+
+// WithInvalidFieldMetrics makes the middleware call onInvalid with the
+// field of the header that failed to parse, see [ParseError], for each
+// request counted as [ResultInvalid] by [WithMetrics].
+func WithInvalidFieldMetrics(onInvalid func(field string)) Option {
+	return func(cfg *config) {
+		cfg.invalidMetrics = onInvalid
+	}
+}
+
+// WithIDGenerator sets the generator for the ids of traces synthesized
+// by [WithGenerateMissing] and spans created by [WithChildSpan], the
+// default is [RandomIDGenerator].
+func WithIDGenerator(gen IDGenerator) Option {
+	return func(cfg *config) {
+		cfg.idGenerator = gen
+	}
+}
+
+// WithIDTimeout makes the middleware wait at most timeout, or until
+// the request context is done, for the [IDGenerator] to create an id.
+// Past that, as [crypto/rand] might block in environments starved of
+// entropy, the id is taken from [math/rand/v2] instead and a warning
+// is logged to the logger set by [WithErrorLogger], or [slog.Default].
+// Those ids are unique in practice but not cryptographically secure, so
+// they must not be relied upon to be unguessable.
+func WithIDTimeout(timeout time.Duration) Option {
+	return func(cfg *config) {
+		cfg.idTimeout = timeout
+	}
+}
+
+// WithOverride makes the middleware parse the request and inject a
+// Trace even if a previous middleware already injected a matching one.
+// By default such requests keep the stored trace, so chaining two
+// middlewares neither redoes the work nor replaces a synthesized trace.
+// Only the parsing, sampling and storing of the trace is skipped, the
+// other options like [WithTraceResponse] or [WithResponseLogging] still
+// apply to the stored trace. The values of [WithStartTime],
+// [WithBaggage] and [WithRequestIDHeader] are stored unless a preceding
+// middleware already did.
+func WithOverride() Option {
+	return func(cfg *config) {
+		cfg.override = true
+	}
+}
+
+// WithResponseLogging makes the middleware log a line with the
+// method, path and response status of each request to logger once the
+// next handler returns. It is logged with the request context, so the
+// extractor adds the trace attributes.
+func WithResponseLogging(logger *slog.Logger) Option {
+	return func(cfg *config) {
+		cfg.responseLogger = logger
+	}
+}
+
+// WithExtractorOptions sets the options for the extractor returned by
+// [Setup], it has no effect on [New].
+func WithExtractorOptions(opts ...ExtractorOption) Option {
+	return func(cfg *config) {
+		cfg.extractorOpts = append(cfg.extractorOpts, opts...)
+	}
+}
+
+// WithSampler makes the middleware call sampler with each request and
+// its Trace, inbound or synthesized, and use the result as the sampled
+// flag of the trace stored in the context. The decision is therefore
+// reflected in the logged attributes and in outgoing requests, see
+// [Transport], even with ForwardRaw as Raw is cleared if the flag
+// changes.
+func WithSampler(sampler func(*http.Request, Trace) bool) Option {
+	return func(cfg *config) {
+		cfg.sampler = sampler
+	}
+}
+
+// WithTracestateSampling makes the middleware take the sampling
+// decision of inbound traces from the tracestate list member of
+// vendorKey, if its value is a boolean like 1 or 0. When present it
+// sets Sampled, overriding the sampled bit of the traceparent header,
+// while Flags keeps the bits as received. Headers and log attributes
+// use the [Trace.EffectiveFlags], and Raw is cleared if the decision
+// differs from the header. A [WithSampler] callback still has the final
+// say.
+func WithTracestateSampling(vendorKey string) Option {
+	return func(cfg *config) {
+		cfg.tracestateSampling = vendorKey
+	}
+}
+
+// WithValidation sets the rules the middleware validates inbound
+// traceparent and tracestate headers with, the default is
+// [ValidationStrict].
+func WithValidation(level ValidationLevel) Option {
+	return func(cfg *config) {
+		cfg.validation = level
+	}
+}
+
+// WithLatencyObserver makes the middleware measure the duration of
+// the wrapped handler and pass it to observe together with the final
+// sampled decision, for example to feed a histogram labeled by it. The
+// duration is taken with the clock set by [WithClock].
+func WithLatencyObserver(observe func(sampled bool, d time.Duration)) Option {
+	return func(cfg *config) {
+		cfg.latencyObserver = observe
+	}
+}
+
+// WithGate makes the middleware call gate with the Trace of each
+// request after parsing and sampling, the zero Trace if there is none.
+// If gate does not proceed the chain is stopped and status is written
+// as the response, statuses outside 100 to 999 like the zero value are
+// replaced by 204 No Content. By default every request proceeds.
+func WithGate(gate func(Trace) (proceed bool, status int)) Option {
+	return func(cfg *config) {
+		cfg.gate = gate
+	}
+}
+
+// WithTrailerFallback makes the middleware look for the traceparent in
+// the request trailers if no header carries one, as done by some gRPC-Web
+// and HTTP/2 streaming clients. The server only populates the trailers
+// after the request body was read completely, so this only finds the
+// trace if a preceding handler consumed the body, for example to
+// buffer it.
+func WithTrailerFallback() Option {
+	return func(cfg *config) {
+		cfg.trailerFallback = true
+	}
+}
+
+// WithDerivedSpanID makes the middleware derive the span-ids it
+// creates from the SHA-256 hash of the key returned by keyFunc, so
+// retries of the same logical request get the same span-id without
+// keeping state. Requests with an empty key get a span-id from the
+// [IDGenerator]. Distinct requests sharing a key share the span-id as
+// well, so the key must identify a single logical request.
+func WithDerivedSpanID(keyFunc func(*http.Request) string) Option {
+	return func(cfg *config) {
+		cfg.derivedSpanKey = keyFunc
+	}
+}
+
+// WithRequestIDHeader makes the middleware store the request id sent
+// in the header name in the request context, see
+// [RequestIDFromContext], with characters other than printable ASCII
+// replaced by '?'. The extractor logs it as requestID. With
+// [WithGenerateMissing] a random request id is created for requests
+// without one.
+func WithRequestIDHeader(name string) Option {
+	return func(cfg *config) {
+		cfg.requestIDHeader = name
+	}
+}
+
+// WithRequireOutermost makes the middleware log a warning once if it
+// detects that another middleware ran before it, as loggers that
+// middleware bound from the request context lack the trace. The
+// warning goes to the logger set by [WithErrorLogger], or
+// [slog.Default]. The detection is a heuristic: the ResponseWriter
+// passed in having an Unwrap method, as provided by most middlewares
+// that wrap it to capture the response status.
+func WithRequireOutermost() Option {
+	return func(cfg *config) {
+		cfg.requireOutermost = true
+	}
+}
+
+// WithServerTiming makes the middleware add a Server-Timing response
+// header with the trace-id to sampled requests, for diagnostics in the
+// browser. Unsampled requests get no header, so the ids are only
+// exposed for the traces actually recorded.
+func WithServerTiming() Option {
+	return func(cfg *config) {
+		cfg.serverTiming = true
+	}
+}
+
+// WithLenientFlags makes the middleware keep a trace whose flags field
+// is malformed, like "zz", as unsampled with Flags 0 instead of
+// dropping the header, so correlation is not lost. The other fields
+// must still be valid.
+func WithLenientFlags() Option {
+	return func(cfg *config) {
+		cfg.lenientFlags = true
+	}
+}
+
+// WithCookieFallback makes the middleware parse the traceparent from
+// the cookie name if no header carries one, for single page apps
+// setting the trace in a cookie. The cookie value is validated like
+// the header.
+func WithCookieFallback(name string) Option {
+	return func(cfg *config) {
+		cfg.cookieName = name
+	}
+}
+
+// WithSpanSynthesis makes the middleware give inbound traces without a
+// span-id, like those taken from X-Ray, a span-id from the
+// [IDGenerator] before storing them, so the span-id logged is the one
+// sent on, instead of [Trace.Header] synthesizing a new one for every
+// header. Such traces are marked by SpanSynthesized, which the
+// extractor logs as spanSynthesized.
+func WithSpanSynthesis() Option {
+	return func(cfg *config) {
+		cfg.synthesizeSpan = true
+	}
+}
+
+// WithTraceResponse makes the middleware set a traceresponse header on
+// the response with the Trace associated with the request, including
+// traces synthesized by [WithGenerateMissing].
+func WithTraceResponse() Option {
+	return func(cfg *config) {
+		cfg.traceResponse = true
+	}
+}
+
+// WithBaggage makes the middleware parse the W3C baggage header and
+// store it in the request context, see [BaggageFromContext].
+func WithBaggage() Option {
+	return func(cfg *config) {
+		cfg.baggage = true
+	}
+}
+
+// WithChildSpan makes the middleware generate a new span-id for each
+// request with an inbound trace. The inbound span-id is kept as
+// ParentSpanID, so propagating the trace to outgoing requests yields a
+// correct parent/child relationship.
+func WithChildSpan() Option {
+	return func(cfg *config) {
+		cfg.childSpan = true
+	}
+}
+
+// WithQueryParamFallback makes the middleware read the traceparent
+// from the query parameter name if the request has no traceparent
+// header. This helps with webhook providers that cannot set headers.
+func WithQueryParamFallback(name string) Option {
+	return func(cfg *config) {
+		cfg.queryParam = name
+	}
+}
+
+// WithSampleRatio makes the middleware mark traces synthesized by
+// [WithGenerateMissing] as sampled with probability ratio. Sampling
+// decisions of inbound traces are never changed.
+func WithSampleRatio(ratio float64) Option {
+	return func(cfg *config) {
+		cfg.sampleRatio = ratio
+	}
+}
+
+// WithSampleSource sets the source of randomness used for
+// [WithSampleRatio]. Using a seeded source makes sampling
+// deterministic, which is mostly useful for tests.
+func WithSampleSource(src rand.Source) Option {
+	return func(cfg *config) {
+		cfg.sampleRand = rand.New(src)
+	}
+}
+
+// sample reports whether a synthesized trace should be sampled.
+func (cfg *config) sample() bool {
+	if cfg.sampleRatio <= 0 {
+		return false
+	}
+	if cfg.sampleRand == nil {
+		return rand.Float64() < cfg.sampleRatio
+	}
+	cfg.sampleMu.Lock()
+	defer cfg.sampleMu.Unlock()
+	return cfg.sampleRand.Float64() < cfg.sampleRatio
+}
+
+// WithSkip makes the middleware pass requests for which skip returns
+// true through without parsing or injecting a trace.
+func WithSkip(skip func(*http.Request) bool) Option {
+	return func(cfg *config) {
+		cfg.skip = skip
+	}
+}
+
+// WithSkipPaths makes the middleware pass requests for the given URL
+// paths, like health checks, through without parsing or injecting a
+// trace.
+func WithSkipPaths(paths ...string) Option {
+	// The paths are read by concurrent requests, the caller may reuse
+	// the slice.
+	paths = slices.Clone(paths)
+	return WithSkip(func(r *http.Request) bool {
+		return slices.Contains(paths, r.URL.Path)
+	})
+}
+
+// WithErrorLogger makes the middleware log a debug message with the
+// reason and a shortened copy of the header to logger when a
+// traceparent header is present but invalid.
+func WithErrorLogger(logger *slog.Logger) Option {
+	return func(cfg *config) {
+		cfg.errorLogger = logger
+	}
+}
+
+// WithStartTime makes the middleware store the arrival time of the
+// request in its context, see [StartTimeFromContext] and
+// [WithElapsed].
+func WithStartTime() Option {
+	return func(cfg *config) {
+		cfg.startTime = true
+	}
+}
+
+// WithClock sets the function the middleware uses to get the current
+// time, the default is [time.Now]. This is mostly useful for tests.
+func WithClock(now func() time.Time) Option {
+	return func(cfg *config) {
+		cfg.now = now
+	}
+}
+
+// WithAlwaysInject makes the middleware inject a zero [Trace] if the
+// request carries no valid trace, so [FromContext] always succeeds for
+// handlers behind the middleware. Unlike [WithGenerateMissing] no ids
+// are made up and the extractors log nothing for the zero trace.
+func WithAlwaysInject() Option {
+	return func(cfg *config) {
+		cfg.alwaysInject = true
+	}
+}
+
+// WithHeaderName makes the middleware read the trace from the header
+// name instead of traceparent, for gateways that rename the header.
+// Set [Transport.HeaderName] to send it under the same name.
+func WithHeaderName(name string) Option {
+	return func(cfg *config) {
+		cfg.headerName = name
+	}
+}
+
+// WithTracestateMaxMembers limits the number of inbound tracestate
+// list members kept to n, the default is [MaxTracestateMembers]. The
+// members past n are dropped and StateTruncated is set on the Trace,
+// which the extractor logs as tracestateTruncated.
+func WithTracestateMaxMembers(n int) Option {
+	return func(cfg *config) {
+		cfg.maxStateMembers = n
+	}
+}
+
+// WithMaxTracestateLen limits the length of the tracestate header the
+// middleware accepts, longer headers are treated as absent. The
+// default is [DefaultMaxTracestateLen].
+func WithMaxTracestateLen(n int) Option {
+	return func(cfg *config) {
+		cfg.maxTracestateLen = n
+	}
+}
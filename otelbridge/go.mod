@@ -0,0 +1,10 @@
+module github.com/jum/slog-traceparent/otelbridge
+
+go 1.24
+
+require (
+	github.com/jum/slog-traceparent v1.1.0
+	go.opentelemetry.io/otel/trace v1.37.0
+)
+
+require go.opentelemetry.io/otel v1.37.0 // indirect
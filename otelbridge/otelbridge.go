@@ -0,0 +1,37 @@
+// Package otelbridge converts between [traceparent.Trace] and the
+// OpenTelemetry [trace.SpanContext]. It lives in its own package to
+// keep the OpenTelemetry dependency optional.
+package otelbridge
+
+import (
+	traceparent "github.com/jum/slog-traceparent"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ToSpanContext converts t into a remote [trace.SpanContext] with the
+// effective flags of t, see [traceparent.Trace.EffectiveFlags].
+func ToSpanContext(t traceparent.Trace) trace.SpanContext {
+	state, _ := trace.ParseTraceState(t.State)
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID(t.ID),
+		SpanID:     trace.SpanID(t.SpanID),
+		TraceFlags: trace.TraceFlags(t.EffectiveFlags()),
+		TraceState: state,
+		Remote:     true,
+	})
+}
+
+// FromSpanContext converts sc into a [traceparent.Trace].
+func FromSpanContext(sc trace.SpanContext) traceparent.Trace {
+	if !sc.TraceID().IsValid() {
+		return traceparent.Trace{}
+	}
+	t := traceparent.Trace{
+		ID:      traceparent.TraceID(sc.TraceID()),
+		SpanID:  traceparent.SpanID(sc.SpanID()),
+		Flags:   byte(sc.TraceFlags()),
+		Sampled: sc.IsSampled(),
+		Random:  byte(sc.TraceFlags())&traceparent.FlagRandom != 0,
+	}
+	return t.WithState(sc.TraceState().String())
+}
@@ -0,0 +1,62 @@
+package otelbridge_test
+
+import (
+	"testing"
+
+	traceparent "github.com/jum/slog-traceparent"
+	"github.com/jum/slog-traceparent/otelbridge"
+)
+
+func TestRoundTrip(t *testing.T) {
+	for _, header := range []string{
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-03",
+	} {
+		in, err := traceparent.ParseTraceparent(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		in = in.WithState("vendor=value")
+		sc := otelbridge.ToSpanContext(in)
+		if !sc.IsValid() || !sc.IsRemote() {
+			t.Fatalf("%s: invalid span context %v", header, sc)
+		}
+		if sc.TraceID().String() != in.ID.String() || sc.SpanID().String() != in.SpanID.String() {
+			t.Errorf("%s: ids %s %s", header, sc.TraceID(), sc.SpanID())
+		}
+		if sc.IsSampled() != in.Sampled {
+			t.Errorf("%s: sampled %v, want %v", header, sc.IsSampled(), in.Sampled)
+		}
+		if got := sc.TraceState().String(); got != "vendor=value" {
+			t.Errorf("%s: tracestate %q", header, got)
+		}
+		out := otelbridge.FromSpanContext(sc)
+		if !out.Equal(in) || out.State != in.State {
+			t.Errorf("%s: round trip got %v state %q, want %v", header, out, out.State, in)
+		}
+		if got := out.Header(); got != header {
+			t.Errorf("Header() = %q, want %q", got, header)
+		}
+	}
+}
+
+func TestToSpanContextSampled(t *testing.T) {
+	id, _ := traceparent.ParseTraceID("4bf92f3577b34da6a3ce929d0e0e4736")
+	span, _ := traceparent.ParseSpanID("00f067aa0ba902b7")
+	sc := otelbridge.ToSpanContext(traceparent.Trace{ID: id, SpanID: span, Sampled: true})
+	if !sc.IsSampled() {
+		t.Error("Sampled not reflected in the span context flags")
+	}
+	in, _ := traceparent.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	in.Sampled = false
+	if otelbridge.ToSpanContext(in).IsSampled() {
+		t.Error("cleared Sampled still sampled in the span context")
+	}
+}
+
+func TestFromSpanContextInvalid(t *testing.T) {
+	if got := otelbridge.FromSpanContext(otelbridge.ToSpanContext(traceparent.Trace{})); !got.IsZero() {
+		t.Errorf("got %v, want the zero Trace", got)
+	}
+}
@@ -0,0 +1,35 @@
+package traceparent
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// Wrap returns the middleware configured by opts, see [New], for use
+// as the outermost handler of a server, so loggers created from the
+// request context by any other middleware see the trace:
+//
+//	http.ListenAndServe(addr, traceparent.Wrap(recovery(logging(mux))))
+//
+// It implies [WithRequireOutermost].
+func Wrap(next http.Handler, opts ...Option) http.Handler {
+	return New(next, append(opts[:len(opts):len(opts)], WithRequireOutermost())...)
+}
+
+// checkOutermost logs a warning once if w looks like it was wrapped by
+// a preceding middleware. Middlewares that capture the response status,
+// like most logging and recovery middlewares, wrap the ResponseWriter
+// and provide an Unwrap method for [http.ResponseController], while the
+// ResponseWriter of [net/http] does not.
+func (cfg *config) checkOutermost(w http.ResponseWriter, r *http.Request) {
+	if _, wrapped := w.(interface{ Unwrap() http.ResponseWriter }); !wrapped {
+		return
+	}
+	cfg.outermostOnce.Do(func() {
+		logger := cfg.errorLogger
+		if logger == nil {
+			logger = slog.Default()
+		}
+		logger.WarnContext(r.Context(), "traceparent: middleware is not outermost, loggers bound by preceding middlewares lack the trace")
+	})
+}
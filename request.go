@@ -0,0 +1,17 @@
+package traceparent
+
+import "net/http"
+
+// Request returns the Trace the middleware stored in the context of r
+// and whether one was present. It uses the default context key, see
+// [FromContext].
+func Request(r *http.Request) (Trace, bool) {
+	return FromContext(r.Context())
+}
+
+// MustRequest returns the Trace stored in the context of r, or the zero
+// Trace if there is none.
+func MustRequest(r *http.Request) Trace {
+	trace, _ := Request(r)
+	return trace
+}
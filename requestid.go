@@ -0,0 +1,32 @@
+package traceparent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+type requestIDContextKeyT struct{}
+
+// ContextWithRequestID returns a Context that stores the request id of
+// a request, see [WithRequestIDHeader].
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKeyT{}, id)
+}
+
+// RequestIDFromContext returns the request id stored in ctx and whether
+// one was present. A nil ctx holds no request id.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	id, ok := ctx.Value(requestIDContextKeyT{}).(string)
+	return id, ok
+}
+
+// newRequestID returns a random request id of 32 hex digits.
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
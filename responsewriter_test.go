@@ -0,0 +1,107 @@
+package traceparent_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	traceparent "github.com/jum/slog-traceparent"
+)
+
+func TestResponseLoggingStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  float64
+	}{
+		{"default", func(w http.ResponseWriter, r *http.Request) {}, http.StatusOK},
+		{"write only", func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "body") }, http.StatusOK},
+		{"explicit", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotFound) }, http.StatusNotFound},
+		{"first wins", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+			w.WriteHeader(http.StatusInternalServerError)
+		}, http.StatusAccepted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(&traceparent.Handler{Next: slog.NewJSONHandler(&buf, nil)})
+			r := httptest.NewRequest(http.MethodGet, "/items", nil)
+			r.Header.Set("traceparent", validHeader)
+			traceparent.New(tt.handler, traceparent.WithResponseLogging(logger)).ServeHTTP(httptest.NewRecorder(), r)
+			var line map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+				t.Fatalf("log %q: %v", buf.String(), err)
+			}
+			if line["status"] != tt.status || line["method"] != http.MethodGet || line["path"] != "/items" || line["traceID"] != traceID {
+				t.Errorf("log line %v, want status %v", line, tt.status)
+			}
+		})
+	}
+}
+
+func TestResponseLoggingFlush(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := traceparent.AccessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("ResponseWriter is not an http.Flusher")
+		}
+		io.WriteString(w, "data: 1\n\n")
+		f.Flush()
+	}), logger)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/events", nil))
+	if !w.Flushed {
+		t.Error("Flush not forwarded")
+	}
+}
+
+func TestResponseLoggingHijack(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	handler := traceparent.AccessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, ok := w.(http.Hijacker)
+		if !ok {
+			t.Error("ResponseWriter is not an http.Hijacker")
+			return
+		}
+		conn, rw, err := h.Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n\r\n")
+		rw.Flush()
+	}), logger)
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(done)
+		handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	reply, _ := io.ReadAll(conn)
+	if !bytes.HasPrefix(reply, []byte("HTTP/1.1 101")) {
+		t.Fatalf("reply %q", reply)
+	}
+	<-done
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("log %q: %v", buf.String(), err)
+	}
+	if line["status"] != float64(http.StatusSwitchingProtocols) {
+		t.Errorf("status %v, want 101", line["status"])
+	}
+}
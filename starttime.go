@@ -0,0 +1,24 @@
+package traceparent
+
+import (
+	"context"
+	"time"
+)
+
+type startTimeContextKeyT struct{}
+
+// ContextWithStartTime returns a Context that stores the start time of
+// a request.
+func ContextWithStartTime(ctx context.Context, start time.Time) context.Context {
+	return context.WithValue(ctx, startTimeContextKeyT{}, start)
+}
+
+// StartTimeFromContext returns the request start time stored in ctx
+// and whether one was present. A nil ctx holds no start time.
+func StartTimeFromContext(ctx context.Context) (time.Time, bool) {
+	if ctx == nil {
+		return time.Time{}, false
+	}
+	start, ok := ctx.Value(startTimeContextKeyT{}).(time.Time)
+	return start, ok
+}
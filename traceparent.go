@@ -9,68 +9,341 @@ package traceparent
 
 import (
 	"context"
-	"log/slog"
-	"net/http"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"strconv"
 	"strings"
-	"time"
 )
 
 // Trace contains tracing information used in logging.
 type Trace struct {
-	ID      string
-	SpanID  string
+	ID     TraceID
+	SpanID SpanID
+	// ParentSpanID is the span-id of the caller when the span-id was
+	// replaced by a child span, see [WithChildSpan].
+	ParentSpanID SpanID
+	// Flags holds the full trace-flags byte as received, Sampled and
+	// Random hold the sampled and random trace-id bits and take
+	// precedence over them, see [Trace.EffectiveFlags].
+	Flags   byte
 	Sampled bool
+	Random  bool
+	// Raw is the traceparent header exactly as received by the
+	// middleware. It is cleared when the middleware replaces the
+	// span-id, see [WithChildSpan], and empty for headers that were only
+	// accepted by lenient rules like [ValidationLenient].
+	Raw string
+	// State is the raw tracestate header that accompanied the
+	// traceparent, StateMembers its parsed list members. The map is
+	// shared by all copies of the Trace, which may be used concurrently,
+	// and must not be modified.
+	State        string
+	StateMembers map[string]string
+	// StateTruncated reports that list members of the tracestate were
+	// dropped, see [WithTracestateMaxMembers].
+	StateTruncated bool
+	// SpanSynthesized reports that the span-id was not received but
+	// created to produce a valid header, see [WithSpanSynthesis].
+	SpanSynthesized bool
 }
 
-// Context returns a Context that stores the Trace.
+// Trace flags defined by the trace context specification.
+const (
+	FlagSampled byte = 1 << iota
+	FlagRandom
+)
+
+// Flag reports whether all bits of mask are set in the effective trace
+// flags, see [Trace.EffectiveFlags].
+func (trace Trace) Flag(mask byte) bool {
+	return trace.EffectiveFlags()&mask == mask
+}
+
+// IDString returns the trace-id as lowercase hex, or an empty string if
+// it is zero, like the string ID field of earlier versions.
+func (trace Trace) IDString() string {
+	if trace.ID.IsZero() {
+		return ""
+	}
+	return trace.ID.String()
+}
+
+// SpanIDString returns the span-id as lowercase hex, or an empty string
+// if it is zero, like the string SpanID field of earlier versions.
+func (trace Trace) SpanIDString() string {
+	if trace.SpanID.IsZero() {
+		return ""
+	}
+	return trace.SpanID.String()
+}
+
+// Valid reports whether the Trace has a trace-id. The span-id may be
+// absent for traces taken from fallback formats like X-Ray.
+func (trace Trace) Valid() bool {
+	return !trace.ID.IsZero()
+}
+
+// IsZero reports whether the Trace is the zero value.
+func (trace Trace) IsZero() bool {
+	return trace.ID.IsZero() && trace.SpanID.IsZero() && trace.ParentSpanID.IsZero() && trace.Flags == 0 &&
+		!trace.Sampled && !trace.Random && trace.Raw == "" && trace.State == "" && trace.StateMembers == nil &&
+		!trace.SpanSynthesized && !trace.StateTruncated
+}
+
+// withSampled returns a copy of the Trace with the sampled flag set to
+// sampled. Raw is cleared if the flag changes as it no longer matches.
+func (trace Trace) withSampled(sampled bool) Trace {
+	if trace.Sampled == sampled {
+		return trace
+	}
+	trace.Sampled = sampled
+	if sampled {
+		trace.Flags |= FlagSampled
+	} else {
+		trace.Flags &^= FlagSampled
+	}
+	trace.Raw = ""
+	return trace
+}
+
+// Context returns a Context that stores the Trace, replacing any
+// Trace stored in ctx before.
 func (trace Trace) Context(ctx context.Context) context.Context {
-	return context.WithValue(ctx, traceContextKeyT{}, trace)
-}
-
-type traceContextKeyT struct{}
-
-// New creates a middleware function that will inject the
-// [Trace] structure into the current requests context. To
-// make this context available to the [log/slog] logging functions, be
-// sure to the the variants including a [context] argument.
-func New(next http.Handler) http.Handler {
-	fn := func(w http.ResponseWriter, r *http.Request) {
-		traceparent := strings.Split(r.Header.Get("traceparent"), "-")
-		if len(traceparent) == 4 && traceparent[0] == "00" {
-			flags, err := strconv.ParseInt(traceparent[3], 16, 8)
-			if err == nil {
-				trace := Trace{
-					ID:      traceparent[1],
-					SpanID:  traceparent[2],
-					Sampled: (flags & 1) != 0,
-				}
-				ctx := trace.Context(r.Context())
-				next.ServeHTTP(w, r.WithContext(ctx))
-			} else {
-				next.ServeHTTP(w, r)
-			}
-		} else {
-			next.ServeHTTP(w, r)
-		}
+	return trace.ContextWithKey(ctx, traceContextKeyT{})
+}
+
+// ContextWithKey returns a Context that stores the Trace under key,
+// see [NewContextKey].
+func (trace Trace) ContextWithKey(ctx context.Context, key any) context.Context {
+	return context.WithValue(ctx, key, trace)
+}
+
+// Header serializes the Trace into a version 00 traceparent header
+// value, or returns an empty string if ID is zero. The span-id is
+// mandatory in a traceparent header, so a random one is synthesized if
+// SpanID is zero, as for traces taken from formats like X-Ray. Each call
+// synthesizes a new one, use [Trace.WithSynthesizedSpan] to send the
+// same span-id in several headers. The flags are the
+// [Trace.EffectiveFlags].
+func (trace Trace) Header() string {
+	if trace.ID.IsZero() {
+		return ""
+	}
+	trace = trace.WithSynthesizedSpan(nil)
+	return "00-" + trace.ID.String() + "-" + trace.SpanID.String() + "-" + hex.EncodeToString([]byte{trace.EffectiveFlags()})
+}
+
+// EffectiveFlags returns the trace flags to propagate: Flags with the
+// sampled and random bits taken from Sampled and Random, which may have
+// been changed after parsing, for example by [WithSampler]. Bits
+// unknown to this package are preserved.
+func (trace Trace) EffectiveFlags() byte {
+	flags := trace.Flags &^ (FlagSampled | FlagRandom)
+	if trace.Sampled {
+		flags |= FlagSampled
+	}
+	if trace.Random {
+		flags |= FlagRandom
+	}
+	return flags
+}
+
+// String returns a compact single-line form of the Trace for debug
+// output, like "trace=<id> span=<span-id> sampled=true".
+func (trace Trace) String() string {
+	return "trace=" + trace.ID.String() + " span=" + trace.SpanID.String() + " sampled=" + strconv.FormatBool(trace.Sampled)
+}
+
+// Equal reports whether the Trace and o identify the same span, that
+// is whether they agree in trace-id, span-id and flags. Raw, the
+// tracestate and the other fields are not compared.
+func (trace Trace) Equal(o Trace) bool {
+	return trace.ID == o.ID && trace.SpanID == o.SpanID && trace.EffectiveFlags() == o.EffectiveFlags()
+}
+
+// WithoutTrace returns a Context in which [FromContext] finds no Trace,
+// for entry points that start a new trace boundary. As the middleware
+// only keeps a Trace stored by a preceding middleware if the context
+// holds one, see [WithOverride], a middleware after WithoutTrace
+// injects the inbound trace again.
+func WithoutTrace(ctx context.Context) context.Context {
+	return context.WithValue(ctx, traceContextKeyT{}, nil)
+}
+
+type traceContextKeyT struct {
+	name string
+}
+
+// NewContextKey returns a context key for storing a Trace separate
+// from the default one used by [Trace.Context] and [FromContext]. Keys
+// created with the same name are equal.
+func NewContextKey(name string) any {
+	return traceContextKeyT{name: name}
+}
+
+// ContextKey returns the default key the Trace is stored under by
+// [Trace.Context] and read from by [FromContext], for frameworks that
+// manage context values themselves. Values stored directly under this
+// key bypass all validation.
+func ContextKey() any {
+	return traceContextKeyT{}
+}
+
+// FromContext returns the Trace stored in ctx and whether one was
+// present.
+func FromContext(ctx context.Context) (Trace, bool) {
+	return FromContextWithKey(ctx, traceContextKeyT{})
+}
+
+// FromContextWithKey returns the Trace stored in ctx under key and
+// whether one was present. A nil ctx holds no Trace.
+func FromContextWithKey(ctx context.Context, key any) (Trace, bool) {
+	if ctx == nil {
+		return Trace{}, false
 	}
-	return http.HandlerFunc(fn)
+	trace, ok := ctx.Value(key).(Trace)
+	return trace, ok
 }
 
-// TraceParentExtractor is function suitable for use as an extractor
-// function for the [github.com/veqryn/slog-context] package to prepend
-// or append the trace information from the context.
-func TraceParentExtractor(ctx context.Context, recordT time.Time, recordLvl slog.Level, recordMsg string) []slog.Attr {
-	trace, ok := ctx.Value(traceContextKeyT{}).(Trace)
-	if !ok || trace.ID == "" {
-		return nil
+// Errors returned by the parse functions like [ParseTraceparent]. The
+// returned errors wrap one of these with details about the failure,
+// use [errors.Is] to test for them.
+var (
+	ErrInvalidVersion = errors.New("traceparent: unsupported version")
+	ErrMalformed      = errors.New("traceparent: malformed header")
+	ErrInvalidFlags   = errors.New("traceparent: invalid trace flags")
+	ErrZeroID         = errors.New("traceparent: all-zero trace-id or span-id")
+	ErrTooLong        = errors.New("traceparent: header too long")
+)
+
+// Fields of a traceparent header reported by [ParseError].
+const (
+	FieldHeader  = "header"
+	FieldVersion = "version"
+	FieldTraceID = "trace-id"
+	FieldSpanID  = "span-id"
+	FieldFlags   = "flags"
+)
+
+// ParseError is returned by [ParseTraceparent] and reports the field of
+// the header that failed to parse, for example to label metrics by it.
+// FieldHeader is used for failures not specific to a field, like the
+// length or layout of the header.
+type ParseError struct {
+	Field string
+	Err   error
+}
+
+// Error implements the error interface.
+func (e *ParseError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns Err, so [errors.Is] finds the error it wraps.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorField returns the field reported by a [ParseError] in the chain
+// of err, or an empty string if there is none.
+func ErrorField(err error) string {
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) {
+		return parseErr.Field
 	}
-	attrs := []slog.Attr{
-		slog.String("traceID", trace.ID),
-		slog.Bool("traceSampled", trace.Sampled),
+	return ""
+}
+
+// ContextWithTraceparent parses header with [ParseTraceparent] and
+// returns a Context storing the resulting Trace. If the header cannot
+// be parsed ctx is returned unchanged. This is intended for entry points
+// like background jobs or message consumers that receive the raw
+// header value.
+func ContextWithTraceparent(ctx context.Context, header string) context.Context {
+	trace, err := ParseTraceparent(header)
+	if err != nil {
+		return ctx
 	}
-	if trace.SpanID != "" {
-		attrs = append(attrs, slog.String("spanID", trace.SpanID))
+	return trace.Context(ctx)
+}
+
+// Header length limits. A version 00 header is exactly versionZeroLen
+// characters long, maxTraceparentLen leaves room for trailing fields of
+// future versions and is checked before any other processing.
+const (
+	versionZeroLen    = 55
+	maxTraceparentLen = 256
+)
+
+// ParseTraceparent parses the value of a traceparent header into a
+// [Trace]. Future versions are parsed as far as version 00 is
+// understood. It does not depend on [net/http] and can be used for
+// headers received over other transports.
+func ParseTraceparent(header string) (Trace, error) {
+	return parseTraceparent(header, ValidationStrict, false)
+}
+
+// parseTraceparent parses header following the rules of level. With
+// lenientFlags a malformed flags field is taken as 00.
+func parseTraceparent(header string, level ValidationLevel, lenientFlags bool) (Trace, error) {
+	if len(header) > maxTraceparentLen {
+		return Trace{}, &ParseError{Field: FieldHeader, Err: fmt.Errorf("%w: %d characters", ErrTooLong, len(header))}
+	}
+	// Some proxies pad header values, the fields themselves must not
+	// contain any whitespace. OTEL does not trim.
+	if level != ValidationOTEL {
+		header = strings.Trim(header, " \t")
+	}
+	if level == ValidationLenient {
+		header = strings.ToLower(header)
+	}
+	// The fields are at fixed offsets, version 2, trace-id 32, span-id
+	// 16 and flags 2 characters, each separated by a dash. Scanning
+	// them in place avoids allocating on every request.
+	if len(header) < versionZeroLen || header[2] != '-' || header[35] != '-' || header[52] != '-' {
+		return Trace{}, &ParseError{Field: FieldHeader, Err: fmt.Errorf("%w: invalid field layout", ErrMalformed)}
+	}
+	// Versions other than the invalid ff are accepted. Version 00 has
+	// exactly four fields, while fields beyond the first four of a
+	// future version are ignored.
+	version := header[0:2]
+	if !isLowerHex(version) || version == "ff" && level != ValidationLenient {
+		return Trace{}, &ParseError{Field: FieldVersion, Err: fmt.Errorf("%w: version %q", ErrInvalidVersion, version)}
+	}
+	// OTEL accepts a version 00 header ending in a single dash.
+	otelDash := level == ValidationOTEL && len(header) == versionZeroLen+1
+	if len(header) > versionZeroLen {
+		if version == "00" && level != ValidationLenient && !otelDash {
+			return Trace{}, &ParseError{Field: FieldHeader, Err: fmt.Errorf("%w: trailing data in version 00", ErrMalformed)}
+		}
+		if header[versionZeroLen] != '-' {
+			return Trace{}, &ParseError{Field: FieldHeader, Err: fmt.Errorf("%w: invalid field layout", ErrMalformed)}
+		}
+	}
+	id, err := ParseTraceID(header[3:35])
+	if err != nil {
+		return Trace{}, &ParseError{Field: FieldTraceID, Err: err}
+	}
+	spanID, err := ParseSpanID(header[36:52])
+	if err != nil {
+		return Trace{}, &ParseError{Field: FieldSpanID, Err: err}
+	}
+	flags, err := ParseFlags(header[53:55])
+	if err != nil && !lenientFlags {
+		return Trace{}, &ParseError{Field: FieldFlags, Err: err}
+	}
+	if level == ValidationOTEL {
+		if version == "00" && flags > FlagRandom {
+			return Trace{}, &ParseError{Field: FieldFlags, Err: fmt.Errorf("%w: flags %q in version 00", ErrInvalidFlags, header[53:55])}
+		}
+		flags &= FlagSampled
 	}
-	return attrs
+	return Trace{
+		ID:      id,
+		SpanID:  spanID,
+		Flags:   flags,
+		Sampled: flags&FlagSampled != 0,
+		Random:  flags&FlagRandom != 0,
+	}, nil
 }
@@ -0,0 +1,373 @@
+package traceparent_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	traceparent "github.com/jum/slog-traceparent"
+)
+
+const (
+	validHeader = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	traceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	spanID      = "00f067aa0ba902b7"
+)
+
+// serve runs handler for a request with the given headers and returns
+// the response and the Trace the next handler saw.
+func serve(t *testing.T, opts []traceparent.Option, header http.Header) (*httptest.ResponseRecorder, traceparent.Trace, bool) {
+	t.Helper()
+	var (
+		got    traceparent.Trace
+		called bool
+	)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, called = traceparent.MustRequest(r), true
+	})
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	for key, values := range header {
+		r.Header[http.CanonicalHeaderKey(key)] = values
+	}
+	w := httptest.NewRecorder()
+	traceparent.New(next, opts...).ServeHTTP(w, r)
+	if !called {
+		return w, traceparent.Trace{}, false
+	}
+	return w, got, true
+}
+
+func attrValues(attrs []slog.Attr) map[string]string {
+	values := make(map[string]string, len(attrs))
+	for _, attr := range attrs {
+		values[attr.Key] = attr.Value.String()
+	}
+	return values
+}
+
+func TestExtractorTraceAndSpanID(t *testing.T) {
+	ctx := traceparent.ContextWithTraceparent(context.Background(), validHeader)
+	attrs := attrValues(traceparent.TraceParentExtractor(ctx, time.Now(), slog.LevelInfo, "msg"))
+	if attrs["traceID"] != traceID {
+		t.Errorf("traceID = %q, want %q", attrs["traceID"], traceID)
+	}
+	if attrs["spanID"] != spanID {
+		t.Errorf("spanID = %q, want %q", attrs["spanID"], spanID)
+	}
+	if attrs["traceSampled"] != "true" {
+		t.Errorf("traceSampled = %q, want true", attrs["traceSampled"])
+	}
+}
+
+func TestParseTraceparentValid(t *testing.T) {
+	tests := []struct {
+		name, header string
+		flags        byte
+	}{
+		{"sampled", validHeader, 0x01},
+		{"not sampled", "00-" + traceID + "-" + spanID + "-00", 0x00},
+		{"future version trailing data", "01-" + traceID + "-" + spanID + "-01-extradata", 0x01},
+		{"future version four fields", "cc-" + traceID + "-" + spanID + "-01", 0x01},
+		{"high flag bits", "01-" + traceID + "-" + spanID + "-ff", 0xff},
+		{"padded", " " + validHeader + "\t", 0x01},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trace, err := traceparent.ParseTraceparent(tt.header)
+			if err != nil {
+				t.Fatalf("ParseTraceparent(%q) = %v", tt.header, err)
+			}
+			if trace.ID.String() != traceID || trace.SpanID.String() != spanID {
+				t.Errorf("ids = %s %s, want %s %s", trace.ID, trace.SpanID, traceID, spanID)
+			}
+			if trace.Flags != tt.flags || trace.Sampled != (tt.flags&traceparent.FlagSampled != 0) {
+				t.Errorf("flags = %02x sampled %v, want %02x", trace.Flags, trace.Sampled, tt.flags)
+			}
+		})
+	}
+}
+
+func TestParseTraceparentInvalid(t *testing.T) {
+	tests := []struct {
+		name, header string
+		err          error
+		field        string
+	}{
+		{"empty", "", traceparent.ErrMalformed, traceparent.FieldHeader},
+		{"short fields", "00-xyz-1-01", traceparent.ErrMalformed, traceparent.FieldHeader},
+		{"short trace-id", "00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01", traceparent.ErrMalformed, traceparent.FieldHeader},
+		{"long trace-id", "00-4bf92f3577b34da6a3ce929d0e0e47366-00f067aa0ba902b7-01", traceparent.ErrMalformed, traceparent.FieldHeader},
+		{"long span-id", "00-" + traceID + "-00f067aa0ba902b77-01", traceparent.ErrMalformed, traceparent.FieldHeader},
+		{"non-hex trace-id", "00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01", traceparent.ErrMalformed, traceparent.FieldTraceID},
+		{"non-hex span-id", "00-" + traceID + "-00f067aa0ba902bz-01", traceparent.ErrMalformed, traceparent.FieldSpanID},
+		{"uppercase trace-id", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", traceparent.ErrMalformed, traceparent.FieldTraceID},
+		{"mixed-case trace-id", "00-4bF92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", traceparent.ErrMalformed, traceparent.FieldTraceID},
+		{"uppercase span-id", "00-" + traceID + "-00F067AA0BA902B7-01", traceparent.ErrMalformed, traceparent.FieldSpanID},
+		{"uppercase flags", "00-" + traceID + "-" + spanID + "-0A", traceparent.ErrInvalidFlags, traceparent.FieldFlags},
+		{"non-hex flags", "00-" + traceID + "-" + spanID + "-zz", traceparent.ErrInvalidFlags, traceparent.FieldFlags},
+		{"zero trace-id", "00-00000000000000000000000000000000-" + spanID + "-01", traceparent.ErrZeroID, traceparent.FieldTraceID},
+		{"zero span-id", "00-" + traceID + "-0000000000000000-01", traceparent.ErrZeroID, traceparent.FieldSpanID},
+		{"version ff", "ff-" + traceID + "-" + spanID + "-01", traceparent.ErrInvalidVersion, traceparent.FieldVersion},
+		{"uppercase version", "0A-" + traceID + "-" + spanID + "-01", traceparent.ErrInvalidVersion, traceparent.FieldVersion},
+		{"version 00 trailing data", validHeader + "-junk", traceparent.ErrMalformed, traceparent.FieldHeader},
+		{"future version no dash", "01-" + traceID + "-" + spanID + "-01junk", traceparent.ErrMalformed, traceparent.FieldHeader},
+		{"oversized", validHeader + "-" + string(make([]byte, 300)), traceparent.ErrTooLong, traceparent.FieldHeader},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := traceparent.ParseTraceparent(tt.header)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ParseTraceparent(%q) = %v, want %v", tt.header, err, tt.err)
+			}
+			if field := traceparent.ErrorField(err); field != tt.field {
+				t.Errorf("ErrorField = %q, want %q", field, tt.field)
+			}
+			if _, got, _ := serve(t, nil, http.Header{"traceparent": {tt.header}}); got.Valid() {
+				t.Errorf("middleware injected %v for %q", got, tt.header)
+			}
+		})
+	}
+}
+
+func TestHeaderRoundTrip(t *testing.T) {
+	for _, header := range []string{
+		validHeader,
+		"00-" + traceID + "-" + spanID + "-00",
+		"00-" + traceID + "-" + spanID + "-05",
+		"00-" + traceID + "-" + spanID + "-ff",
+	} {
+		trace, err := traceparent.ParseTraceparent(header)
+		if err != nil {
+			t.Fatalf("ParseTraceparent(%q) = %v", header, err)
+		}
+		if got := trace.Header(); got != header {
+			t.Errorf("Header() = %q, want %q", got, header)
+		}
+	}
+	if got := (traceparent.Trace{}).Header(); got != "" {
+		t.Errorf("zero Trace Header() = %q, want empty", got)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	_, got, called := serve(t, nil, http.Header{"traceparent": {validHeader}})
+	if !called || got.ID.String() != traceID || got.SpanID.String() != spanID || !got.Sampled {
+		t.Fatalf("got %v, called %v", got, called)
+	}
+	if got.Raw != validHeader {
+		t.Errorf("Raw = %q, want %q", got.Raw, validHeader)
+	}
+	if _, got, called := serve(t, nil, nil); !called || got.Valid() {
+		t.Errorf("missing header: got %v, called %v", got, called)
+	}
+}
+
+func TestTraceResponse(t *testing.T) {
+	opts := []traceparent.Option{traceparent.WithTraceResponse()}
+	w, _, _ := serve(t, opts, http.Header{"traceparent": {validHeader}})
+	if got := w.Header().Get("traceresponse"); got != validHeader {
+		t.Errorf("traceresponse = %q, want %q", got, validHeader)
+	}
+	w, got, _ := serve(t, append(opts, traceparent.WithGenerateMissing()), nil)
+	if want := got.Header(); want == "" || w.Header().Get("traceresponse") != want {
+		t.Errorf("synthesized traceresponse = %q, want %q", w.Header().Get("traceresponse"), want)
+	}
+	if w, _, _ := serve(t, opts, nil); w.Header().Get("traceresponse") != "" {
+		t.Errorf("traceresponse without trace = %q", w.Header().Get("traceresponse"))
+	}
+}
+
+func TestStrict(t *testing.T) {
+	opts := []traceparent.Option{
+		traceparent.WithStrict(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "bad traceparent", http.StatusBadRequest)
+		})),
+		traceparent.WithB3Fallback(),
+	}
+	tests := []struct {
+		name   string
+		header http.Header
+		status int
+		called bool
+	}{
+		{"malformed", http.Header{"traceparent": {"00-xyz-1-01"}}, http.StatusBadRequest, false},
+		{"valid", http.Header{"traceparent": {validHeader}}, http.StatusOK, true},
+		{"absent", nil, http.StatusOK, true},
+		{"malformed with valid fallback", http.Header{
+			"traceparent": {"garbage"},
+			"b3":          {traceID + "-" + spanID + "-1"},
+		}, http.StatusOK, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, _, called := serve(t, opts, tt.header)
+			if w.Code != tt.status || called != tt.called {
+				t.Errorf("status %d called %v, want %d %v", w.Code, called, tt.status, tt.called)
+			}
+		})
+	}
+}
+
+func TestGate(t *testing.T) {
+	gate := func(status int) []traceparent.Option {
+		return []traceparent.Option{traceparent.WithGate(func(trace traceparent.Trace) (bool, int) {
+			return trace.Sampled, status
+		})}
+	}
+	unsampled := "00-" + traceID + "-" + spanID + "-00"
+	w, _, called := serve(t, gate(http.StatusNoContent), http.Header{"traceparent": {unsampled}})
+	if called || w.Code != http.StatusNoContent {
+		t.Errorf("unsampled: status %d called %v, want 204 not called", w.Code, called)
+	}
+	w, got, called := serve(t, gate(http.StatusNoContent), http.Header{"traceparent": {validHeader}})
+	if !called || w.Code != http.StatusOK || !got.Sampled {
+		t.Errorf("sampled: status %d called %v, want pass through", w.Code, called)
+	}
+	w, _, called = serve(t, gate(0), http.Header{"traceparent": {unsampled}})
+	if called || w.Code != http.StatusNoContent {
+		t.Errorf("zero status: status %d called %v, want 204 not called", w.Code, called)
+	}
+}
+
+func TestServerTiming(t *testing.T) {
+	opts := []traceparent.Option{traceparent.WithServerTiming()}
+	w, _, _ := serve(t, opts, http.Header{"traceparent": {validHeader}})
+	if got, want := w.Header().Get("Server-Timing"), `traceparent;desc="`+traceID+`"`; got != want {
+		t.Errorf("Server-Timing = %q, want %q", got, want)
+	}
+	w, _, _ = serve(t, opts, http.Header{"traceparent": {"00-" + traceID + "-" + spanID + "-00"}})
+	if got := w.Header().Get("Server-Timing"); got != "" {
+		t.Errorf("unsampled Server-Timing = %q, want none", got)
+	}
+}
+
+// TestValidationOTEL uses the extraction vectors of the OTEL trace
+// context propagator.
+func TestValidationOTEL(t *testing.T) {
+	opts := []traceparent.Option{traceparent.WithValidation(traceparent.ValidationOTEL)}
+	valid := []struct {
+		header  string
+		sampled bool
+	}{
+		{"00-" + traceID + "-" + spanID + "-00", false},
+		{"00-" + traceID + "-" + spanID + "-01", true},
+		{"02-" + traceID + "-" + spanID + "-00", false},
+		{"02-" + traceID + "-" + spanID + "-01", true},
+		{"02-" + traceID + "-" + spanID + "-09", true},
+		{"02-" + traceID + "-" + spanID + "-08", false},
+		{"02-" + traceID + "-" + spanID + "-00-XYZxsf09", false},
+		{"00-" + traceID + "-" + spanID + "-00-", false},
+		{"03-" + traceID + "-" + spanID + "-00-", false},
+	}
+	for _, tt := range valid {
+		_, got, _ := serve(t, opts, http.Header{"traceparent": {tt.header}, "tracestate": {"invalid$@#=invalid"}})
+		if got.ID.String() != traceID || got.SpanID.String() != spanID {
+			t.Errorf("%q: got %v", tt.header, got)
+			continue
+		}
+		if got.Sampled != tt.sampled || got.Random || got.Flags&^traceparent.FlagSampled != 0 {
+			t.Errorf("%q: flags %02x sampled %v, want only sampled %v", tt.header, got.Flags, got.Sampled, tt.sampled)
+		}
+		if got.State != "" || got.StateMembers != nil {
+			t.Errorf("%q: invalid tracestate %q kept", tt.header, got.State)
+		}
+	}
+	for _, header := range []string{
+		"0000-00000000000000000000000000000000-0000000000000000-01",
+		"00-ab00000000000000000000000000000000-cd00000000000000-01",
+		"00-ab000000000000000000000000000000-cd0000000000000000-01",
+		"00-ab000000000000000000000000000000-cd00000000000000-0100",
+		"qw-00000000000000000000000000000000-0000000000000000-01",
+		"00-qw000000000000000000000000000000-cd00000000000000-01",
+		"00-ab000000000000000000000000000000-qw00000000000000-01",
+		"00-ab000000000000000000000000000000-cd00000000000000-qw",
+		"A0-00000000000000000000000000000000-0000000000000000-01",
+		"00-AB000000000000000000000000000000-cd00000000000000-01",
+		"00-ab000000000000000000000000000000-CD00000000000000-01",
+		"00-ab000000000000000000000000000000-cd00000000000000-A1",
+		"00-00000000000000000000000000000000-0000000000000000-01",
+		"00-ab000000000000000000000000000000-cd00000000000000-09",
+		"00-" + traceID + "-" + spanID,
+		"00-" + traceID + "-" + spanID + "-",
+		"00-" + traceID + "-" + spanID + "-00--",
+		" 00-" + traceID + "-" + spanID + "-01",
+	} {
+		if _, got, _ := serve(t, opts, http.Header{"traceparent": {header}}); got.Valid() {
+			t.Errorf("%q: got %v, want rejected", header, got)
+		}
+	}
+}
+
+func TestSamplerOutbound(t *testing.T) {
+	var sent []string
+	transport := &traceparent.Transport{
+		Base: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			sent = append(sent, r.Header.Get("traceparent"))
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		}),
+		ForwardRaw: true,
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for range 2 {
+			req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, "http://example.com/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := transport.RoundTrip(req); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+	handler := traceparent.New(next, traceparent.WithSampler(func(*http.Request, traceparent.Trace) bool {
+		return true
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("traceparent", "00-"+traceID+"-"+spanID+"-00")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if len(sent) != 2 {
+		t.Fatalf("sent %d requests, want 2", len(sent))
+	}
+	for _, header := range sent {
+		if want := "00-" + traceID + "-" + spanID + "-01"; header != want {
+			t.Errorf("outbound traceparent = %q, want %q", header, want)
+		}
+	}
+}
+
+func TestIDRoundTrip(t *testing.T) {
+	id, err := traceparent.ParseTraceID(traceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.String() != traceID {
+		t.Errorf("TraceID.String() = %q, want %q", id, traceID)
+	}
+	span, err := traceparent.ParseSpanID(spanID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if span.String() != spanID {
+		t.Errorf("SpanID.String() = %q, want %q", span, spanID)
+	}
+	trace := traceparent.Trace{ID: id, SpanID: span}
+	if trace.IDString() != traceID || trace.SpanIDString() != spanID {
+		t.Errorf("IDString, SpanIDString = %q, %q", trace.IDString(), trace.SpanIDString())
+	}
+	if got := (traceparent.Trace{}); got.IDString() != "" || got.SpanIDString() != "" {
+		t.Errorf("zero Trace ids = %q, %q, want empty", got.IDString(), got.SpanIDString())
+	}
+	for _, s := range []string{"", "4BF92F3577B34DA6A3CE929D0E0E4736", "00000000000000000000000000000000", traceID + "0"} {
+		if _, err := traceparent.ParseTraceID(s); err == nil {
+			t.Errorf("ParseTraceID(%q) succeeded", s)
+		}
+	}
+	for _, s := range []string{"", "00F067AA0BA902B7", "0000000000000000", spanID[1:]} {
+		if _, err := traceparent.ParseSpanID(s); err == nil {
+			t.Errorf("ParseSpanID(%q) succeeded", s)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+// Package traceparenttest provides helpers for tests of handlers that
+// use the [traceparent.Trace] of a request.
+package traceparenttest
+
+import (
+	"context"
+	"net/http"
+
+	traceparent "github.com/jum/slog-traceparent"
+)
+
+// WithTrace returns a Context storing a Trace with the given hex
+// trace-id and span-id. It panics if an id is invalid.
+func WithTrace(ctx context.Context, id, span string, sampled bool) context.Context {
+	trace := traceparent.Trace{
+		Sampled: sampled,
+	}
+	var err error
+	if trace.ID, err = traceparent.ParseTraceID(id); err != nil {
+		panic(err)
+	}
+	if trace.SpanID, err = traceparent.ParseSpanID(span); err != nil {
+		panic(err)
+	}
+	if sampled {
+		trace.Flags = traceparent.FlagSampled
+	}
+	return trace.Context(ctx)
+}
+
+// Request returns a shallow copy of r with trace stored in its
+// context, as if r had passed the [traceparent.New] middleware.
+func Request(r *http.Request, trace traceparent.Trace) *http.Request {
+	return r.WithContext(trace.Context(r.Context()))
+}
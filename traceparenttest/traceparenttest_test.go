@@ -0,0 +1,68 @@
+package traceparenttest_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	traceparent "github.com/jum/slog-traceparent"
+	"github.com/jum/slog-traceparent/traceparenttest"
+)
+
+const (
+	traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	spanID  = "00f067aa0ba902b7"
+)
+
+func TestWithTrace(t *testing.T) {
+	for _, sampled := range []bool{true, false} {
+		trace, ok := traceparent.FromContext(traceparenttest.WithTrace(context.Background(), traceID, spanID, sampled))
+		if !ok {
+			t.Fatal("FromContext found no trace")
+		}
+		if trace.ID.String() != traceID || trace.SpanID.String() != spanID || trace.Sampled != sampled {
+			t.Errorf("got %v, want sampled %v", trace, sampled)
+		}
+		if want := "00-" + traceID + "-" + spanID + map[bool]string{true: "-01", false: "-00"}[sampled]; trace.Header() != want {
+			t.Errorf("Header() = %q, want %q", trace.Header(), want)
+		}
+	}
+}
+
+func TestWithTraceInvalid(t *testing.T) {
+	for _, tt := range []struct{ id, span string }{
+		{"", spanID},
+		{traceID, ""},
+		{"00000000000000000000000000000000", spanID},
+		{traceID, "xyz"},
+		{"4BF92F3577B34DA6A3CE929D0E0E4736", spanID},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("WithTrace(%q, %q) did not panic", tt.id, tt.span)
+				}
+			}()
+			traceparenttest.WithTrace(context.Background(), tt.id, tt.span, true)
+		}()
+	}
+}
+
+func TestRequest(t *testing.T) {
+	want, err := traceparent.ParseTraceparent("00-" + traceID + "-" + spanID + "-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	got, ok := traceparent.FromContext(traceparenttest.Request(r, want).Context())
+	if !ok || !got.Equal(want) {
+		t.Errorf("FromContext = %v, %v, want %v", got, ok, want)
+	}
+	if _, ok := traceparent.FromContext(r.Context()); ok {
+		t.Error("Request modified the original request")
+	}
+	if got := traceparent.MustRequest(traceparenttest.Request(r, want)); !got.Equal(want) {
+		t.Errorf("MustRequest = %v, want %v", got, want)
+	}
+}
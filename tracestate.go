@@ -0,0 +1,181 @@
+package traceparent
+
+import (
+	"encoding/hex"
+	"slices"
+	"strings"
+)
+
+// ParseTracestate parses the value of a tracestate header into a map
+// of its comma-separated key=value list members. Parsing is lenient,
+// malformed list members are skipped rather than rejecting the whole
+// header.
+func ParseTracestate(header string) map[string]string {
+	_, members, _ := parseTracestate(header, false, 0)
+	return members
+}
+
+// DefaultMaxTracestateLen is the default limit for the length of a
+// tracestate header, longer headers are treated as absent.
+const DefaultMaxTracestateLen = 512
+
+// WithState returns a copy of the Trace with State and StateMembers
+// set from the tracestate header value. Malformed list members are
+// dropped from both so that only valid characters are retained,
+// headers longer than [DefaultMaxTracestateLen] are ignored.
+func (trace Trace) WithState(header string) Trace {
+	if len(header) > DefaultMaxTracestateLen {
+		return trace
+	}
+	return trace.withState(header)
+}
+
+func (trace Trace) withState(header string) Trace {
+	trace.State, trace.StateMembers, _ = parseTracestate(header, false, 0)
+	return trace
+}
+
+// FilterTracestate returns the tracestate header value state with only
+// the list members whose key is one of keys, keeping their order.
+func FilterTracestate(state string, keys ...string) string {
+	var kept []string
+	for _, member := range strings.Split(state, ",") {
+		key, _, _ := strings.Cut(member, "=")
+		if slices.Contains(keys, strings.TrimSpace(key)) {
+			kept = append(kept, member)
+		}
+	}
+	return strings.Join(kept, ",")
+}
+
+// MaxTracestateMembers is the maximum number of tracestate list
+// members allowed by the trace context specification.
+const MaxTracestateMembers = 32
+
+// PromoteTracestate returns the tracestate header value state with the
+// list member for vendor moved to the front, as required for the entry
+// of a service participating in the trace. The result is truncated to
+// [MaxTracestateMembers] members.
+func PromoteTracestate(state, vendor string) string {
+	if state == "" {
+		return ""
+	}
+	members := strings.Split(state, ",")
+	if vendor != "" {
+		for i, member := range members {
+			key, _, _ := strings.Cut(member, "=")
+			if strings.TrimSpace(key) == vendor {
+				copy(members[1:i+1], members[:i])
+				members[0] = member
+				break
+			}
+		}
+	}
+	if len(members) > MaxTracestateMembers {
+		members = members[:MaxTracestateMembers]
+	}
+	return strings.Join(members, ",")
+}
+
+// parseTracestate returns the valid list members of header, both
+// re-joined into a header value and as a map. If strict is set any
+// invalid member invalidates the whole header. If maxMembers is
+// positive members past it are dropped and truncated is reported.
+func parseTracestate(header string, strict bool, maxMembers int) (state string, members map[string]string, truncated bool) {
+	if header == "" {
+		return "", nil, false
+	}
+	var valid []string
+	members = make(map[string]string)
+	for _, member := range strings.Split(header, ",") {
+		member = strings.Trim(member, " \t")
+		if member == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(member, "=")
+		_, dup := members[key]
+		if !ok || !validTracestateKey(key) || !validTracestateValue(value) || dup {
+			if strict {
+				return "", nil, false
+			}
+			continue
+		}
+		if maxMembers > 0 && len(valid) == maxMembers {
+			truncated = true
+			break
+		}
+		members[key] = value
+		valid = append(valid, member)
+	}
+	if len(valid) == 0 {
+		return "", nil, truncated
+	}
+	return strings.Join(valid, ","), members, truncated
+}
+
+// validTracestateKey reports whether key is a simple or multi-tenant
+// tracestate key as defined by the trace context specification.
+func validTracestateKey(key string) bool {
+	tenant, system, multi := strings.Cut(key, "@")
+	if !multi {
+		return len(key) <= 256 && validKeyChars(key, false)
+	}
+	return len(tenant) <= 241 && validKeyChars(tenant, true) &&
+		len(system) <= 14 && validKeyChars(system, false)
+}
+
+func validKeyChars(s string, digitFirst bool) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z':
+		case '0' <= c && c <= '9':
+			if i == 0 && !digitFirst {
+				return false
+			}
+		case i > 0 && (c == '_' || c == '-' || c == '*' || c == '/'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// validTracestateValue reports whether value only contains printable
+// ASCII characters other than ',' and '=' and does not end in a space.
+func validTracestateValue(value string) bool {
+	if value == "" || len(value) > 256 || value[len(value)-1] == ' ' {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c < 0x20 || c > 0x7e || c == ',' || c == '=' {
+			return false
+		}
+	}
+	return true
+}
+
+// percentDecode decodes the percent-encoded characters of s, leaving
+// invalid sequences like "%zz" unchanged.
+func percentDecode(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) {
+			if c, err := hex.DecodeString(s[i+1 : i+3]); err == nil {
+				b.WriteByte(c[0])
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
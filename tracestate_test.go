@@ -0,0 +1,129 @@
+package traceparent_test
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	traceparent "github.com/jum/slog-traceparent"
+)
+
+func TestTracestateSampling(t *testing.T) {
+	tests := []struct {
+		name, flags, state string
+		sampled            bool
+		raw                bool
+	}{
+		{"tracestate samples", "00", "v=1", true, false},
+		{"tracestate drops", "01", "v=0", false, false},
+		{"agreeing", "01", "v=true", true, true},
+		{"not a boolean", "00", "v=maybe", false, true},
+		{"other vendor", "00", "w=1", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := "00-" + traceID + "-" + spanID + "-" + tt.flags
+			var sent string
+			transport := &traceparent.Transport{
+				Base: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+					sent = r.Header.Get("traceparent")
+					return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+				}),
+				ForwardRaw: true,
+			}
+			var got traceparent.Trace
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = traceparent.MustRequest(r)
+				req := httptest.NewRequestWithContext(r.Context(), http.MethodGet, "http://example.com/", nil)
+				if _, err := transport.RoundTrip(req); err != nil {
+					t.Fatal(err)
+				}
+			})
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("traceparent", header)
+			r.Header.Set("tracestate", tt.state)
+			traceparent.New(next, traceparent.WithTracestateSampling("v")).ServeHTTP(httptest.NewRecorder(), r)
+
+			if got.Sampled != tt.sampled {
+				t.Errorf("Sampled = %v, want %v", got.Sampled, tt.sampled)
+			}
+			if want := header[:len(header)-2]; sent[:len(sent)-2] != want {
+				t.Errorf("sent %q, want the inbound ids", sent)
+			}
+			if sampled := sent[len(sent)-2:] == "01"; sampled != tt.sampled {
+				t.Errorf("sent %q, want sampled %v", sent, tt.sampled)
+			}
+			if (got.Raw != "") != tt.raw {
+				t.Errorf("Raw = %q, want kept %v", got.Raw, tt.raw)
+			}
+			if got.Flags&traceparent.FlagSampled != 0 != (tt.flags == "01") {
+				t.Errorf("Flags = %02x, want the received %s", got.Flags, tt.flags)
+			}
+			attrs := attrValues(traceparent.NewExtractor(traceparent.WithFlagsKey("flags"))(got.Context(context.Background()), time.Now(), slog.LevelInfo, ""))
+			if want := sent[len(sent)-2:]; attrs["flags"] != want {
+				t.Errorf("flags attr = %q, want %q", attrs["flags"], want)
+			}
+		})
+	}
+}
+
+func TestTracestate(t *testing.T) {
+	state := "congo=t61rcWkgMzE, rojo=00f067aa0ba902b7,bad key=x,acme@tenant=opaque/+"
+	_, got, _ := serve(t, nil, http.Header{"traceparent": {validHeader}, "tracestate": {state}})
+	if want := "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7,acme@tenant=opaque/+"; got.State != want {
+		t.Errorf("State = %q, want %q", got.State, want)
+	}
+	want := map[string]string{"congo": "t61rcWkgMzE", "rojo": "00f067aa0ba902b7", "acme@tenant": "opaque/+"}
+	if len(got.StateMembers) != len(want) {
+		t.Errorf("StateMembers = %v, want %v", got.StateMembers, want)
+	}
+	for key, value := range want {
+		if got.StateMembers[key] != value {
+			t.Errorf("StateMembers[%q] = %q, want %q", key, got.StateMembers[key], value)
+		}
+	}
+
+	_, got, _ = serve(t, []traceparent.Option{traceparent.WithAlwaysInject()}, http.Header{"tracestate": {state}})
+	if got.State != "" || got.StateMembers != nil {
+		t.Errorf("tracestate %q accepted without a traceparent", got.State)
+	}
+}
+
+func TestTracestateMaxMembers(t *testing.T) {
+	members := make([]string, 40)
+	for i := range members {
+		members[i] = fmt.Sprintf("k%02d=v", i)
+	}
+	state := strings.Join(members, ",")
+	header := http.Header{"traceparent": {validHeader}, "tracestate": {state}}
+	extract := traceparent.NewExtractor()
+
+	_, got, _ := serve(t, nil, header)
+	if want := strings.Join(members[:32], ","); got.State != want || len(got.StateMembers) != 32 || !got.StateTruncated {
+		t.Errorf("State = %q with %d members, truncated %v, want the first 32", got.State, len(got.StateMembers), got.StateTruncated)
+	}
+	if _, ok := got.StateMembers["k32"]; ok {
+		t.Error("member k32 kept past the limit")
+	}
+	if attrs := attrValues(extract(got.Context(context.Background()), time.Now(), slog.LevelInfo, "")); attrs["tracestateTruncated"] != "true" {
+		t.Errorf("attrs = %v, want tracestateTruncated", attrs)
+	}
+
+	_, got, _ = serve(t, []traceparent.Option{traceparent.WithTracestateMaxMembers(5)}, header)
+	if len(got.StateMembers) != 5 || !got.StateTruncated {
+		t.Errorf("%d members, truncated %v, want 5", len(got.StateMembers), got.StateTruncated)
+	}
+
+	_, got, _ = serve(t, nil, http.Header{"traceparent": {validHeader}, "tracestate": {strings.Join(members[:32], ",")}})
+	if len(got.StateMembers) != 32 || got.StateTruncated {
+		t.Errorf("%d members, truncated %v, want all 32", len(got.StateMembers), got.StateTruncated)
+	}
+	if attrs := attrValues(extract(got.Context(context.Background()), time.Now(), slog.LevelInfo, "")); attrs["tracestateTruncated"] != "" {
+		t.Errorf("attrs = %v, want no tracestateTruncated", attrs)
+	}
+}
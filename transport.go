@@ -0,0 +1,71 @@
+package traceparent
+
+import "net/http"
+
+// Transport is an [http.RoundTripper] that adds a traceparent header
+// for the [Trace] stored in the request context to outgoing requests.
+// Traces without a span-id get a random one for each request, use
+// [WithSpanSynthesis] to have all requests carry the same one. Like
+// [http.Transport] it is safe for concurrent use, its fields must not
+// be modified once in use.
+type Transport struct {
+	// Base is the RoundTripper used to make the actual request, if nil
+	// [http.DefaultTransport] is used.
+	Base http.RoundTripper
+	// ForwardRaw makes the Transport forward the Raw inbound
+	// traceparent header verbatim when present instead of serializing
+	// the Trace, avoiding any normalization.
+	ForwardRaw bool
+	// HeaderName is the name of the header the trace is sent in, the
+	// default is traceparent. It matches [WithHeaderName] on the
+	// receiving side.
+	HeaderName string
+	// Datadog makes the Transport also send the trace in the Datadog
+	// x-datadog-* headers, see [InjectDatadog].
+	Datadog bool
+	// TracestateAllowlist limits the tracestate list members sent to
+	// those with the given keys, if empty all members are sent.
+	TracestateAllowlist []string
+	// TracestateVendor is the tracestate key of this service. If
+	// set, its list member is moved to the front of the tracestate
+	// sent, as the most recently updated entry.
+	TracestateVendor string
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	trace, ok := FromContext(req.Context())
+	if !ok || !trace.Valid() {
+		return base.RoundTrip(req)
+	}
+	// A span-less trace still needs a span-id in the traceparent header,
+	// all headers sent must carry the same one.
+	trace = trace.WithSynthesizedSpan(nil)
+	// A RoundTripper must not modify the request it was given.
+	req = req.Clone(req.Context())
+	name := t.HeaderName
+	if name == "" {
+		name = "traceparent"
+	}
+	header := trace.Header()
+	if t.ForwardRaw && trace.Raw != "" {
+		header = trace.Raw
+	}
+	req.Header.Set(name, header)
+	state := trace.State
+	if len(t.TracestateAllowlist) > 0 {
+		state = FilterTracestate(state, t.TracestateAllowlist...)
+	}
+	state = PromoteTracestate(state, t.TracestateVendor)
+	if state != "" {
+		req.Header.Set("tracestate", state)
+	}
+	if t.Datadog {
+		InjectDatadog(trace, HeaderCarrier(req.Header))
+	}
+	return base.RoundTrip(req)
+}
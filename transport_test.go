@@ -0,0 +1,95 @@
+package traceparent_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	traceparent "github.com/jum/slog-traceparent"
+)
+
+func TestTransport(t *testing.T) {
+	var received traceparent.Trace
+	var header http.Header
+	backend := httptest.NewServer(traceparent.New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, header = traceparent.MustRequest(r), r.Header
+	})))
+	defer backend.Close()
+	client := &http.Client{Transport: &traceparent.Transport{Base: backend.Client().Transport}}
+
+	get := func(ctx context.Context) {
+		t.Helper()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, backend.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if req.Header.Get("traceparent") != "" {
+			t.Error("Transport modified the request it was given")
+		}
+	}
+
+	trace, err := traceparent.ParseTraceparent(validHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	get(trace.WithState("congo=t61rcWkgMzE").Context(context.Background()))
+	if header.Get("traceparent") != validHeader || !received.Equal(trace) {
+		t.Errorf("backend received %q", header.Get("traceparent"))
+	}
+	if header.Get("tracestate") != "congo=t61rcWkgMzE" {
+		t.Errorf("backend received tracestate %q", header.Get("tracestate"))
+	}
+
+	get(context.Background())
+	if _, ok := header["Traceparent"]; ok || received.Valid() {
+		t.Errorf("traceparent %q sent without a trace", header.Get("traceparent"))
+	}
+}
+
+func TestTransportForwardRaw(t *testing.T) {
+	// A future version with unknown flags and an extra field is valid
+	// but not what Header produces.
+	raw := "cc-" + traceID + "-" + spanID + "-09-future"
+	for _, tt := range []struct {
+		name string
+		opts []traceparent.Option
+		raw  bool
+	}{
+		{"raw", nil, true},
+		{"child span", []traceparent.Option{traceparent.WithChildSpan()}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent, header string
+			transport := &traceparent.Transport{
+				Base: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+					sent = r.Header.Get("traceparent")
+					return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+				}),
+				ForwardRaw: true,
+			}
+			handler := traceparent.New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				trace := traceparent.MustRequest(r)
+				header = trace.Header()
+				req := httptest.NewRequestWithContext(r.Context(), http.MethodGet, "http://example.com/", nil)
+				if _, err := transport.RoundTrip(req); err != nil {
+					t.Fatal(err)
+				}
+			}), tt.opts...)
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("traceparent", raw)
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+			if header == raw {
+				t.Fatalf("Header() = %q reproduces the inbound header", header)
+			}
+			if want := map[bool]string{true: raw, false: header}[tt.raw]; sent != want {
+				t.Errorf("sent %q, want %q", sent, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package traceparent
+
+// ValidationLevel selects the rules the middleware validates inbound
+// headers with, see [WithValidation].
+type ValidationLevel int
+
+const (
+	// ValidationLenient accepts any traceparent header that can be
+	// parsed: hex digits may be uppercase, the version may be ff and a
+	// version 00 header may carry trailing fields. Ids must still be
+	// non-zero, as a zero trace is never logged. This eases migrating
+	// from senders that do not follow the specification.
+	ValidationLenient ValidationLevel = iota
+	// ValidationStrict applies the rules of the trace context
+	// specification. This is the default.
+	ValidationStrict
+	// ValidationParanoid applies the rules of ValidationStrict and in
+	// addition drops the whole tracestate header if any list member is
+	// malformed or duplicated, instead of just skipping the member.
+	ValidationParanoid
+	// ValidationOTEL mirrors the TraceContext propagator of
+	// OpenTelemetry: headers are not trimmed, version 00 headers with
+	// flags other than 00, 01 and 02 are rejected, all flags but the
+	// sampled bit are cleared and the whole tracestate header is dropped
+	// if any list member is invalid.
+	ValidationOTEL
+)
@@ -0,0 +1,61 @@
+package traceparent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseXRay parses the value of an AWS X-Ray X-Amzn-Trace-Id header of
+// the form Root=1-<epoch>-<random>;Parent=<id>;Sampled=1 into a
+// [Trace]. The Root is mapped to the trace-id by dropping the version
+// and dashes, Parent to the span-id. Parent is missing for requests
+// originating at a load balancer, in which case SpanID is zero.
+func ParseXRay(header string) (Trace, error) {
+	var trace Trace
+	var root, parent string
+	for _, field := range strings.Split(header, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+		switch key {
+		case "Root":
+			version, id, ok := strings.Cut(value, "-")
+			if !ok || version != "1" || len(id) != 33 || id[8] != '-' {
+				return Trace{}, fmt.Errorf("%w: invalid x-ray root", ErrMalformed)
+			}
+			root = id[:8] + id[9:]
+		case "Parent":
+			parent = value
+		case "Sampled":
+			if value == "1" {
+				trace.Flags = FlagSampled
+				trace.Sampled = true
+			}
+		}
+	}
+	var err error
+	if trace.ID, err = ParseTraceID(root); err != nil {
+		return Trace{}, err
+	}
+	if parent != "" {
+		if trace.SpanID, err = ParseSpanID(parent); err != nil {
+			return Trace{}, err
+		}
+	}
+	return trace, nil
+}
+
+// XRayHeader serializes the Trace into an AWS X-Ray X-Amzn-Trace-Id
+// header value. It returns an empty string if ID is zero.
+func (trace Trace) XRayHeader() string {
+	if trace.ID.IsZero() {
+		return ""
+	}
+	id := trace.ID.String()
+	header := "Root=1-" + id[:8] + "-" + id[8:]
+	if !trace.SpanID.IsZero() {
+		header += ";Parent=" + trace.SpanID.String()
+	}
+	if trace.Sampled {
+		return header + ";Sampled=1"
+	}
+	return header + ";Sampled=0"
+}
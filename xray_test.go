@@ -0,0 +1,62 @@
+package traceparent_test
+
+import (
+	"net/http"
+	"testing"
+
+	traceparent "github.com/jum/slog-traceparent"
+)
+
+const xrayHeader = "Root=1-4bf92f35-77b34da6a3ce929d0e0e4736;Parent=00f067aa0ba902b7;Sampled=1"
+
+func TestXRayFallback(t *testing.T) {
+	opts := []traceparent.Option{traceparent.WithXRayFallback()}
+	_, got, _ := serve(t, opts, http.Header{"X-Amzn-Trace-Id": {xrayHeader}})
+	if got.ID.String() != traceID || got.SpanID.String() != spanID || !got.Sampled {
+		t.Errorf("trace %v, want %s", got, validHeader)
+	}
+	// A load balancer adds a Root without a Parent.
+	_, got, _ = serve(t, opts, http.Header{"X-Amzn-Trace-Id": {"Root=1-4bf92f35-77b34da6a3ce929d0e0e4736"}})
+	if got.ID.String() != traceID || !got.SpanID.IsZero() || got.Sampled {
+		t.Errorf("trace %v, want a span-less unsampled trace", got)
+	}
+	other := "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-00"
+	_, got, _ = serve(t, opts, http.Header{"traceparent": {other}, "X-Amzn-Trace-Id": {xrayHeader}})
+	if got.Header() != other {
+		t.Errorf("trace %v, want the traceparent %s", got, other)
+	}
+	_, got, _ = serve(t, []traceparent.Option{traceparent.WithB3Fallback(), traceparent.WithXRayFallback()},
+		http.Header{"b3": {"0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-0"}, "X-Amzn-Trace-Id": {xrayHeader}})
+	if got.ID.String() != "0af7651916cd43dd8448eb211c80319c" {
+		t.Errorf("trace %v, want the b3 trace", got)
+	}
+	if _, got, _ = serve(t, nil, http.Header{"X-Amzn-Trace-Id": {xrayHeader}}); got.Valid() {
+		t.Errorf("x-ray parsed without the option: %v", got)
+	}
+}
+
+func TestXRayHeader(t *testing.T) {
+	trace, err := traceparent.ParseXRay(xrayHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := trace.XRayHeader(); got != xrayHeader {
+		t.Errorf("XRayHeader() = %q, want %q", got, xrayHeader)
+	}
+	if got := (traceparent.Trace{ID: trace.ID}).XRayHeader(); got != "Root=1-4bf92f35-77b34da6a3ce929d0e0e4736;Sampled=0" {
+		t.Errorf("XRayHeader() without span = %q", got)
+	}
+	if got := (traceparent.Trace{}).XRayHeader(); got != "" {
+		t.Errorf("XRayHeader() of the zero Trace = %q", got)
+	}
+	for _, header := range []string{
+		"",
+		"Root=2-4bf92f35-77b34da6a3ce929d0e0e4736",
+		"Root=1-4bf92f3577b34da6a3ce929d0e0e4736",
+		"Root=1-4bf92f35-77b34da6a3ce929d0e0e4736;Parent=xyz",
+	} {
+		if trace, err := traceparent.ParseXRay(header); err == nil {
+			t.Errorf("ParseXRay(%q) = %v, want an error", header, trace)
+		}
+	}
+}
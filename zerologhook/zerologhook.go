@@ -0,0 +1,24 @@
+// Package zerologhook adds the trace information to zerolog events. It
+// lives in its own package to keep the zerolog dependency optional.
+package zerologhook
+
+import (
+	traceparent "github.com/jum/slog-traceparent"
+	"github.com/rs/zerolog"
+)
+
+// Hook is a [zerolog.Hook] adding the fields of the
+// [traceparent.Trace] found in the event context, set using
+// [zerolog.Event.Ctx], to each event.
+type Hook struct{}
+
+// Run implements [zerolog.Hook].
+func (Hook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	fields, ok := traceparent.TraceFields(e.GetCtx())
+	if !ok {
+		return
+	}
+	for i := 0; i+1 < len(fields); i += 2 {
+		e.Str(fields[i], fields[i+1])
+	}
+}